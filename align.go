@@ -0,0 +1,253 @@
+package grid
+
+import (
+	"strings"
+)
+
+// The horizontal alignment of the text of a cell. The general alignment
+// right aligns numbers and dates, centers booleans and errors and left
+// aligns text.
+type HAlign int
+
+const (
+	AlignGeneral HAlign = iota
+	AlignLeft
+	AlignCenter
+	AlignRight
+)
+
+// The vertical alignment of the text of a cell. The zero value is the
+// default bottom alignment.
+type VAlign int
+
+const (
+	AlignBottom VAlign = iota + 1
+	AlignMiddle
+	AlignTop
+)
+
+// The alignment of the text of a cell. Text that is not wrapped
+// overflows into empty neighbouring cells.
+type Alignment struct {
+	Horizontal HAlign
+	Vertical   VAlign
+	Wrap       bool
+}
+
+// Parse an alignment name such as "center" or "top". Returns false if
+// the name is unknown.
+func parseHAlign(s string) (HAlign, bool) {
+	switch strings.ToLower(s) {
+	case "", "general":
+		return AlignGeneral, true
+	case "left":
+		return AlignLeft, true
+	case "center":
+		return AlignCenter, true
+	case "right":
+		return AlignRight, true
+	}
+	return AlignGeneral, false
+}
+
+func parseVAlign(s string) (VAlign, bool) {
+	switch strings.ToLower(s) {
+	case "":
+		return 0, true
+	case "bottom":
+		return AlignBottom, true
+	case "middle":
+		return AlignMiddle, true
+	case "top":
+		return AlignTop, true
+	}
+	return 0, false
+}
+
+// Set the alignment of a cell. The alignment is part of the cell
+// style, the other fields of the style are kept.
+func (sh *Sheet) SetCellAlignment(row, col int, align Alignment) {
+	a := Address{row, col}
+	s := sh.styles.cells[a]
+	s.Alignment = align
+	sh.styles.setCell(a, s)
+}
+
+// Set the alignment of a column.
+func (sh *Sheet) SetColumnAlignment(col int, align Alignment) {
+	s := sh.styles.columns[col]
+	s.Alignment = align
+	sh.styles.setColumn(col, s)
+}
+
+// Set the alignment of a range of cells.
+func (sh *Sheet) SetRangeAlignment(r Range, align Alignment) {
+	sh.SetRangeStyle(r, CellStyle{Alignment: align})
+}
+
+// The alignment of a cell from its style.
+func (sh *Sheet) GetCellAlignment(row, col int) Alignment {
+	return sh.styles.get(Address{row, col}).Alignment
+}
+
+// The horizontal alignment a cell is drawn with, the general alignment
+// resolved by the type of the value.
+func (c *cell) hAlign(align Alignment) HAlign {
+	if align.Horizontal != AlignGeneral {
+		return align.Horizontal
+	}
+	switch c.val.kind {
+	case NumberKind, DateKind:
+		return AlignRight
+	case BoolKind, ErrorKind:
+		return AlignCenter
+	}
+	return AlignLeft
+}
+
+// Split text into the lines that fit a width. Lines are broken between
+// words, words wider than the width are broken between grapheme
+// clusters. Line breaks in the text are kept.
+func wrapText(s string, w float64, width func(string) float64) []string {
+	lines := []string{}
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			next := word
+			if line != "" {
+				next = line + " " + word
+			}
+			if width(next) <= w {
+				line = next
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// Break a long word over as many lines as needed.
+			line = ""
+			for i := 0; i < len(word); {
+				j := nextGrapheme(word, i)
+				if line != "" && width(line+word[i:j]) > w {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += word[i:j]
+				i = j
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// The top of a block of text of height th aligned in a cell at y with
+// the height h and padding pad.
+func textTop(v VAlign, y, h, th, pad int) int {
+	switch v {
+	case AlignTop:
+		return y + pad
+	case AlignMiddle:
+		return y + (h-th)/2
+	}
+	return y + h - pad - th
+}
+
+// A Container that implements AlignmentStyler can override the
+// alignment of the cell styles. CellAlignment returns false to keep the
+// alignment of the style.
+type AlignmentStyler interface {
+	CellAlignment(row, col int) (Alignment, bool)
+}
+
+// The alignment a cell is drawn with. An AlignmentStyler can override
+// the alignment of the cell style.
+func (sh *Sheet) alignment(row, col int) Alignment {
+	if s, ok := sh.styler.(AlignmentStyler); ok {
+		if a, ok := s.CellAlignment(row, col); ok {
+			return a
+		}
+	}
+	return sh.GetCellAlignment(row, col)
+}
+
+// The horizontal extent a cell's text can be drawn in. Text that is
+// not wrapped overflows into the empty cells next to it: to the right
+// for left aligned text, to the left for right aligned text and to both
+// sides for centered text. Numbers don't overflow.
+func (sh *Sheet) overflowSpan(c *cell, h HAlign, need float64) (int, int) {
+	x := sh.cols.offset(c.col)
+	left, right := x, x+sh.cols.sizeOf(c.col)
+	if m, ok := sh.mergeAt(Address{c.row, c.col}); ok {
+		// Merged cells don't overflow.
+		return left, sh.cols.offset(m.To.Col + 1)
+	}
+	if c.val.kind == NumberKind || c.val.kind == DateKind || c == sh.editCell {
+		return left, right
+	}
+	// The overflow stops at the edge of the view-port or of the frozen
+	// columns.
+	first, last := sh.visibleCols()
+	if c.col < sh.frozenCols {
+		first, last = 0, sh.frozenCols-1
+	}
+	extra := need - float64(right-left)
+	if h == AlignCenter {
+		extra /= 2
+	}
+	if h != AlignLeft {
+		for col := c.col - 1; col >= first && float64(x-left) < extra && !sh.filled(Address{c.row, col}); col-- {
+			left -= sh.cols.sizeOf(col)
+		}
+	}
+	if h != AlignRight {
+		end := x + sh.cols.sizeOf(c.col)
+		for col := c.col + 1; col <= last && float64(right-end) < extra && !sh.filled(Address{c.row, col}); col++ {
+			right += sh.cols.sizeOf(col)
+		}
+	}
+	return left, right
+}
+
+// Draw the text of a cell with its alignment and padding. The cell is
+// at the view coordinates x and y with the size w and h.
+func (sh *Sheet) drawText(r Renderer, c *cell, x, y, w, h int, style CellStyle) {
+	align := sh.alignment(c.row, c.col)
+	pad := style.Padding
+	ha := c.hAlign(align)
+	str := c.text()
+	lineH := r.LineHeight()
+
+	// The edit cell is a single left aligned line with the caret.
+	if c == sh.editCell {
+		ty := textTop(align.Vertical, y, h, lineH, pad)
+		r.FillText(str, x+pad, ty, AlignLeft)
+		sh.drawEditor(r, x+pad, y, h)
+		return
+	}
+
+	left, right := x, x+w
+	lines := []string{str}
+	if align.Wrap {
+		lines = wrapText(str, float64(w-2*pad), r.MeasureText)
+	} else {
+		l, rt := sh.overflowSpan(c, ha, r.MeasureText(str)+float64(2*pad))
+		left, right = x+l-sh.cols.offset(c.col), x+rt-sh.cols.offset(c.col)
+		lines[0] = fitText(r, str, right-left-2*pad)
+	}
+
+	r.Save()
+	r.Clip(left, y, right-left, h)
+	tx := left + pad
+	switch ha {
+	case AlignCenter:
+		tx = x + w/2
+	case AlignRight:
+		tx = right - pad
+	}
+	ty := textTop(align.Vertical, y, h, lineH*len(lines), pad)
+	for i, line := range lines {
+		r.FillText(line, tx, ty+i*lineH, ha)
+	}
+	r.Restore()
+}
@@ -0,0 +1,146 @@
+package grid
+
+import (
+	"sort"
+)
+
+// The borders of the sides of a cell.
+type Borders struct {
+	Top, Right, Bottom, Left Border
+}
+
+// The border of a side of a cell. The dash style is "solid", "dashed",
+// "dotted" or "none" to remove a border set at a lower style level. A
+// zero Border is not set.
+type Border struct {
+	Color string
+	Width int
+	Dash  string
+}
+
+// Check if the border is drawn.
+func (b Border) visible() bool {
+	return b.Width > 0 && b.Dash != "none"
+}
+
+// The line dash of the border.
+func (b Border) lineDash() []float64 {
+	w := float64(b.Width)
+	switch b.Dash {
+	case "dashed":
+		return []float64{3 * w, 2 * w}
+	case "dotted":
+		return []float64{w, w}
+	}
+	return nil
+}
+
+// Override the sides of b that are set in o.
+func (b Borders) overlay(o Borders) Borders {
+	sides := []*Border{&b.Top, &b.Right, &b.Bottom, &b.Left}
+	for i, side := range []Border{o.Top, o.Right, o.Bottom, o.Left} {
+		if side != (Border{}) {
+			*sides[i] = side
+		}
+	}
+	return b
+}
+
+// Draw a border around a range. Only the outer sides of the cells on
+// the edges of the range are set.
+func (sh *Sheet) OutlineRange(r Range, b Border) {
+	r = NewRange(r.From, r.To)
+	from, to := r.From, r.To
+	sh.styles.setRange(Range{from, Address{from.Row, to.Col}}, CellStyle{Borders: Borders{Top: b}})
+	sh.styles.setRange(Range{Address{to.Row, from.Col}, to}, CellStyle{Borders: Borders{Bottom: b}})
+	sh.styles.setRange(Range{from, Address{to.Row, from.Col}}, CellStyle{Borders: Borders{Left: b}})
+	sh.styles.setRange(Range{Address{from.Row, to.Col}, to}, CellStyle{Borders: Borders{Right: b}})
+}
+
+// Draw a border on every side of every cell of a range.
+func (sh *Sheet) SetRangeBorders(r Range, b Border) {
+	sh.SetRangeStyle(r, CellStyle{Borders: Borders{b, b, b, b}})
+}
+
+// Remove the borders of the cells of a range.
+func (sh *Sheet) ClearRangeBorders(r Range) {
+	none := Border{Dash: "none"}
+	sh.SetRangeStyle(r, CellStyle{Borders: Borders{none, none, none, none}})
+}
+
+// A border line between two points in view coordinates.
+type borderLine struct {
+	x0, y0, x1, y1 float64
+	b              Border
+}
+
+// Draw the borders of the cells of a range in a pane. The lines are
+// centered on the grid lines so the borders of neighbouring cells meet.
+// Where two cells have a border on the same side the wider one is drawn
+// on top.
+func (sh *Sheet) drawCellBorders(r Renderer, p pane, rg Range) {
+	if sh.styles.empty() {
+		return
+	}
+	lines := []borderLine{}
+	add := func(c Range, b Borders) {
+		if b == (Borders{}) {
+			return
+		}
+		x := float64(p.vx + sh.cols.offset(c.From.Col) - p.gx)
+		y := float64(p.vy + sh.rows.offset(c.From.Row) - p.gy)
+		w := float64(sh.cols.offset(c.To.Col+1) - sh.cols.offset(c.From.Col))
+		h := float64(sh.rows.offset(c.To.Row+1) - sh.rows.offset(c.From.Row))
+		for _, l := range []borderLine{
+			{x, y, x + w, y, b.Top},
+			{x, y + h, x + w, y + h, b.Bottom},
+			{x, y, x, y + h, b.Left},
+			{x + w, y, x + w, y + h, b.Right},
+		} {
+			if l.b.visible() {
+				lines = append(lines, l)
+			}
+		}
+	}
+	for row := rg.From.Row; row <= rg.To.Row; row++ {
+		for col := rg.From.Col; col <= rg.To.Col; col++ {
+			a := Address{row, col}
+			if _, ok := sh.mergeAt(a); !ok {
+				add(Range{a, a}, sh.styles.get(a).Borders)
+			}
+		}
+	}
+	// The borders of merged cells are taken from the cells on the
+	// edges of the merge.
+	for _, m := range sh.merges {
+		if overlaps(m, rg) {
+			b := sh.styles.get(m.From).Borders
+			b.Bottom = sh.styles.get(Address{m.To.Row, m.From.Col}).Borders.Bottom
+			b.Right = sh.styles.get(Address{m.From.Row, m.To.Col}).Borders.Right
+			add(m, b)
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].b.Width < lines[j].b.Width
+	})
+	r.Save()
+	for _, l := range lines {
+		color := l.b.Color
+		if color == "" {
+			color = "black"
+		}
+		// Odd widths are moved half a pixel to draw sharp lines.
+		offset := 0.0
+		if l.b.Width%2 == 1 {
+			offset = 0.5
+		}
+		r.SetStrokeStyle(color)
+		r.SetLineWidth(float64(l.b.Width))
+		r.SetLineDash(l.b.lineDash())
+		r.BeginPath()
+		r.MoveTo(l.x0+offset, l.y0+offset)
+		r.LineTo(l.x1+offset, l.y1+offset)
+		r.Stroke()
+	}
+	r.Restore()
+}
@@ -0,0 +1,239 @@
+package grid
+
+// The formula dependency graph. It records the ranges each formula
+// cell references so that a change to a cell only recalculates the
+// formulas that depend on it.
+type depGraph struct {
+	precedents map[Address][]Range
+	// Formulas referencing a single cell, keyed by that cell.
+	cells map[Address]map[Address]bool
+	// The multi-cell ranges referenced by each formula.
+	ranges map[Address][]Range
+	// Formulas referencing multi-cell ranges, keyed by the tiles of the
+	// cell index the ranges overlap, so a change only checks the ranges
+	// near the changed cell.
+	tiles map[tile]map[Address]bool
+	// Formulas referencing ranges over more than maxRangeTiles tiles,
+	// such as whole columns. They are checked for every change.
+	large map[Address]bool
+}
+
+// The most tiles a range is indexed in.
+const maxRangeTiles = 1024
+
+func newDepGraph() depGraph {
+	return depGraph{map[Address][]Range{}, map[Address]map[Address]bool{}, map[Address][]Range{},
+		map[tile]map[Address]bool{}, map[Address]bool{}}
+}
+
+// Add or remove the formula at f to the index of the range r.
+func (d *depGraph) index(f Address, r Range, add bool) {
+	from, to := tileOf(r.From), tileOf(r.To)
+	if (to.row-from.row+1)*(to.col-from.col+1) > maxRangeTiles {
+		if add {
+			d.large[f] = true
+		} else {
+			delete(d.large, f)
+		}
+		return
+	}
+	for row := from.row; row <= to.row; row++ {
+		for col := from.col; col <= to.col; col++ {
+			t := tile{row, col}
+			if add {
+				if d.tiles[t] == nil {
+					d.tiles[t] = map[Address]bool{}
+				}
+				d.tiles[t][f] = true
+				continue
+			}
+			delete(d.tiles[t], f)
+			if len(d.tiles[t]) == 0 {
+				delete(d.tiles, t)
+			}
+		}
+	}
+}
+
+// Set the ranges referenced by the formula at f.
+func (d *depGraph) set(f Address, refs []Range) {
+	d.remove(f)
+	d.precedents[f] = refs
+	for _, r := range refs {
+		if r.From != r.To {
+			d.ranges[f] = append(d.ranges[f], r)
+			d.index(f, r, true)
+			continue
+		}
+		if d.cells[r.From] == nil {
+			d.cells[r.From] = map[Address]bool{}
+		}
+		d.cells[r.From][f] = true
+	}
+}
+
+// Remove the formula at f from the graph.
+func (d *depGraph) remove(f Address) {
+	for _, r := range d.precedents[f] {
+		if r.From == r.To {
+			delete(d.cells[r.From], f)
+			if len(d.cells[r.From]) == 0 {
+				delete(d.cells, r.From)
+			}
+			continue
+		}
+		d.index(f, r, false)
+	}
+	delete(d.precedents, f)
+	delete(d.ranges, f)
+}
+
+// The formulas that directly reference the cell at a.
+func (d *depGraph) dependents(a Address) []Address {
+	deps := []Address{}
+	found := map[Address]bool{}
+	for f := range d.cells[a] {
+		deps = append(deps, f)
+		found[f] = true
+	}
+	check := func(formulas map[Address]bool) {
+		for f := range formulas {
+			if found[f] {
+				continue
+			}
+			for _, r := range d.ranges[f] {
+				if r.Contains(a) {
+					deps = append(deps, f)
+					found[f] = true
+					break
+				}
+			}
+		}
+	}
+	check(d.tiles[tileOf(a)])
+	check(d.large)
+	return deps
+}
+
+// Update the parsed formula and the dependency graph after the value
+// of a cell changed, then recalculate the cells that depend on it.
+func (sh *Sheet) cellChanged(c *cell) {
+	a := Address{c.row, c.col}
+	// Cells that are only selected are not part of the data yet.
+	if sh.data[a] != c {
+		return
+	}
+	sh.parse(c)
+	sh.recalc(a)
+}
+
+// Parse the formula of a cell and record its references.
+func (sh *Sheet) parse(c *cell) {
+	a := Address{c.row, c.col}
+	c.expr = nil
+	sh.deps.remove(a)
+	if !isFormula(c.value) {
+		c.val = ParseValue(c.value)
+		return
+	}
+	if n, ok := parseFormula(c.value[1:]); ok {
+		c.expr = n
+		sh.deps.set(a, refs(n))
+	}
+}
+
+// Rebuild the dependency graph and recalculate every formula. Used
+// when cells move to new addresses.
+func (sh *Sheet) recalcAll() {
+	sh.deps = newDepGraph()
+	changed := []Address{}
+	for a, c := range sh.data {
+		sh.parse(c)
+		changed = append(changed, a)
+	}
+	sh.recalc(changed...)
+}
+
+// Recalculate the formulas affected by the changed cells in
+// topological order. Formulas that are part of, or depend on, a
+// reference cycle show #CIRC!. The container is notified of every
+// recomputed cell.
+func (sh *Sheet) recalc(changed ...Address) {
+	// Find the changed formulas and every formula downstream of them.
+	dirty := map[Address]bool{}
+	queue := []Address{}
+	for _, a := range changed {
+		if c, ok := sh.data[a]; ok && isFormula(c.value) {
+			dirty[a] = true
+		}
+		queue = append(queue, a)
+	}
+	for len(queue) > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		for _, f := range sh.deps.dependents(a) {
+			if !dirty[f] {
+				dirty[f] = true
+				queue = append(queue, f)
+			}
+		}
+	}
+
+	// Order the dirty formulas so each is evaluated after the dirty
+	// formulas it references.
+	indegree := map[Address]int{}
+	for a := range dirty {
+		for _, f := range sh.deps.dependents(a) {
+			if dirty[f] {
+				indegree[f]++
+			}
+		}
+	}
+	order := []Address{}
+	for a := range dirty {
+		if indegree[a] == 0 {
+			order = append(order, a)
+		}
+	}
+	for i := 0; i < len(order); i++ {
+		for _, f := range sh.deps.dependents(order[i]) {
+			if !dirty[f] {
+				continue
+			}
+			indegree[f]--
+			if indegree[f] == 0 {
+				order = append(order, f)
+			}
+		}
+	}
+
+	lookup := func(a Address) Value {
+		c, ok := sh.data[a]
+		if !ok {
+			return Value{}
+		}
+		return c.val
+	}
+	recalculated := []CellContent{}
+
+	// Anything left with references to resolve is in a cycle.
+	for a := range dirty {
+		if indegree[a] > 0 {
+			sh.data[a].val = ErrorVal(errCirc)
+			recalculated = append(recalculated, sh.data[a])
+		}
+	}
+	for _, a := range order {
+		c := sh.data[a]
+		if c.expr == nil {
+			c.val = ErrorVal(errParse)
+		} else {
+			c.val = evalFormula(c.expr, lookup)
+		}
+		recalculated = append(recalculated, c)
+	}
+
+	if l, ok := sh.listener.(RecalcListener); ok && len(recalculated) > 0 {
+		l.CellsRecalculated(recalculated)
+	}
+}
@@ -0,0 +1,82 @@
+package grid
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDependents(t *testing.T) {
+	d := newDepGraph()
+	formulas := map[string][]string{
+		"Z1":  {"A1:B3", "C2"},
+		"Z2":  {"A1", "A1:A10"},
+		"Z3":  {"P1:R2"},
+		"Z4":  {"AA1:AZ1"},
+		"Z5":  {"A1:XFD1"},
+		"ZZ1": {"B2:B2"},
+		"ZZ2": {"C1:C1073741824", "Q1:Q2"},
+	}
+	for f, refs := range formulas {
+		ranges := []Range{}
+		for _, ref := range refs {
+			ranges = append(ranges, r1(ref))
+		}
+		d.set(a1(f), ranges)
+	}
+	dependents := func(ref string) string {
+		deps := []string{}
+		for _, f := range d.dependents(a1(ref)) {
+			deps = append(deps, f.String())
+		}
+		sort.Strings(deps)
+		return strings.Join(deps, " ")
+	}
+	tests := []struct {
+		ref, want string
+	}{
+		{"A1", "Z1 Z2 Z5"},
+		{"B2", "Z1 ZZ1"},
+		{"C2", "Z1 ZZ2"},
+		{"A5", "Z2"},
+		{"C1000000", "ZZ2"},
+		{"Q2", "Z3 ZZ2"},
+		{"Q3", ""},
+		{"AM1", "Z4 Z5"},
+		{"XFD1", "Z5"},
+		{"XFD2", ""},
+	}
+	for _, tt := range tests {
+		if got := dependents(tt.ref); got != tt.want {
+			t.Errorf("dependents(%s) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+
+	if len(d.large) != 1 || !d.large[a1("ZZ2")] {
+		t.Errorf("large range formulas = %v, want ZZ2", d.large)
+	}
+
+	for f := range formulas {
+		d.remove(a1(f))
+	}
+	if len(d.cells) != 0 || len(d.ranges) != 0 || len(d.tiles) != 0 || len(d.large) != 0 {
+		t.Errorf("removing every formula left %d cells, %d ranges, %d tiles and %d large ranges",
+			len(d.cells), len(d.ranges), len(d.tiles), len(d.large))
+	}
+}
+
+// A change only checks the range formulas near the changed cell, not
+// every formula with a range in the same columns.
+func TestDependentsRowRanges(t *testing.T) {
+	d := newDepGraph()
+	for row := 0; row < 10000; row++ {
+		d.set(Address{row, 3}, []Range{{Address{row, 0}, Address{row, 2}}})
+	}
+	a := a1("B5000")
+	if deps := d.dependents(a); len(deps) != 1 || deps[0] != a1("D5000") {
+		t.Errorf("dependents(B5000) = %v, want D5000", deps)
+	}
+	if n := len(d.tiles[tileOf(a)]); n > tileRows {
+		t.Errorf("%d formulas checked for B5000, want at most %d", n, tileRows)
+	}
+}
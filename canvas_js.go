@@ -0,0 +1,114 @@
+package grid
+
+import (
+	"syscall/js"
+)
+
+// A Renderer drawing onto a canvas 2D context. It is the default
+// Renderer of the grid.
+type canvasRenderer struct {
+	ctx js.Value
+}
+
+// Create a Renderer for a canvas 2D context.
+func NewCanvasRenderer(ctx js.Value) Renderer {
+	return &canvasRenderer{ctx}
+}
+
+func (c *canvasRenderer) Save() {
+	c.ctx.Call("save")
+}
+
+func (c *canvasRenderer) Restore() {
+	c.ctx.Call("restore")
+}
+
+func (c *canvasRenderer) Clip(x, y, w, h int) {
+	c.ctx.Call("beginPath")
+	c.ctx.Call("rect", x, y, w, h)
+	c.ctx.Call("clip")
+}
+
+func (c *canvasRenderer) SetFont(font string) {
+	c.ctx.Set("font", font)
+}
+
+func (c *canvasRenderer) SetFillStyle(color string) {
+	c.ctx.Set("fillStyle", color)
+}
+
+// The canvas returns colors in the #rrggbb form.
+func (c *canvasRenderer) FillStyle() string {
+	return c.ctx.Get("fillStyle").String()
+}
+
+func (c *canvasRenderer) SetStrokeStyle(color string) {
+	c.ctx.Set("strokeStyle", color)
+}
+
+func (c *canvasRenderer) SetLineWidth(width float64) {
+	c.ctx.Set("lineWidth", width)
+}
+
+func (c *canvasRenderer) SetLineDash(dash []float64) {
+	segments := []interface{}{}
+	for _, d := range dash {
+		segments = append(segments, d)
+	}
+	c.ctx.Call("setLineDash", segments)
+}
+
+func (c *canvasRenderer) SetShadow(color string, blur float64) {
+	c.ctx.Set("shadowColor", color)
+	c.ctx.Set("shadowBlur", blur)
+}
+
+func (c *canvasRenderer) FillRect(x, y, w, h int) {
+	c.ctx.Call("fillRect", x, y, w, h)
+}
+
+func (c *canvasRenderer) StrokeRect(x, y, w, h int) {
+	c.ctx.Call("strokeRect", x, y, w, h)
+}
+
+func (c *canvasRenderer) BeginPath() {
+	c.ctx.Call("beginPath")
+}
+
+func (c *canvasRenderer) MoveTo(x, y float64) {
+	c.ctx.Call("moveTo", x, y)
+}
+
+func (c *canvasRenderer) LineTo(x, y float64) {
+	c.ctx.Call("lineTo", x, y)
+}
+
+func (c *canvasRenderer) Stroke() {
+	c.ctx.Call("stroke")
+}
+
+func (c *canvasRenderer) FillText(text string, x, y int, align HAlign) {
+	switch align {
+	case AlignCenter:
+		c.ctx.Set("textAlign", "center")
+	case AlignRight:
+		c.ctx.Set("textAlign", "right")
+	default:
+		c.ctx.Set("textAlign", "left")
+	}
+	c.ctx.Set("textBaseline", "top")
+	c.ctx.Call("fillText", text, x, y)
+}
+
+func (c *canvasRenderer) MeasureText(text string) float64 {
+	return c.ctx.Call("measureText", text).Get("width").Float()
+}
+
+func (c *canvasRenderer) LineHeight() int {
+	tm := c.ctx.Call("measureText", "M")
+	h := tm.Get("fontBoundingBoxAscent").Int() + tm.Get("fontBoundingBoxDescent").Int()
+	if h <= 0 {
+		h = 18
+	}
+	return h
+}
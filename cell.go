@@ -2,13 +2,13 @@ package grid
 
 // A cell of the grid.
 type cell struct {
-	x           int
-	y           int
-	row         int
-	col         int
-	value       string
-	editing     bool
-	grid	*grid
+	row     int
+	col     int
+	value   string
+	editing bool
+	sheet   *Sheet
+	expr    node  // parsed formula
+	val     Value // typed value, the computed result for formulas
 }
 
 // The address of a cell.
@@ -22,6 +22,8 @@ type CellContent interface {
 	GetCol() int
 	GetValue() string
 	SetValue(v string)
+	GetTypedValue() Value
+	SetTypedValue(v Value)
 }
 
 func (c *cell) GetRow() int {
@@ -37,53 +39,94 @@ func (c *cell) GetValue() string {
 }
 
 func (c *cell) SetValue(v string) {
+	a := Address{c.row, c.col}
+	if c.sheet.data[a] == c {
+		c.sheet.history.record(valueChange{a, c.value, v, true, true})
+	}
 	c.value = v
+	c.sheet.cellChanged(c)
+}
+
+func (c *cell) GetTypedValue() Value {
+	return c.val
+}
+
+func (c *cell) SetTypedValue(v Value) {
+	c.SetValue(v.input())
 }
 
-// Draw an individual grid cell. If there is a container allow it to set
-// the cell and font styles.
-// TODO: Consider making the fgColor and fontColor fields of the grid
-// struct so that there can be a single call to the container.SetCellStyles
-// that sets both for the grid.
-func (c *cell) draw() {
-	// Set default cell styles.
-	c.grid.ctx.Set("font", "15px arial")
-	fgColor := "white"
-	c.grid.ctx.Set("fillStyle", fgColor)
-
-	// Notify the container that the cell is being drawn so any custom 
-	// cell styles can be applied to the canvas ctx.
-	if c.grid.container != nil {
-		c.grid.container.SetCellStyles(c.row, c.col)
+// The text displayed in the cell. The edit cell shows the editor text,
+// other cells their formatted value or formula result.
+func (c *cell) text() string {
+	if c == c.sheet.editCell {
+		return c.sheet.editor.display()
+	}
+	return formatValue(c.val, c.sheet.formats.get(Address{c.row, c.col}))
+}
+
+// Draw an individual grid cell with its style. If there is a cellStyler
+// allow it to change the cell and font styles set on the Renderer.
+func (sh *Sheet) drawCell(r Renderer, c *cell) {
+	x, y := sh.toView(sh.addressToCoords(c.row, c.col))
+	w, h := sh.cellSize(Address{c.row, c.col})
+	_, merged := sh.mergeAt(Address{c.row, c.col})
+	style := sh.GetCellStyle(c.row, c.col)
+
+	r.SetFont(style.font())
+	r.SetFillStyle(style.Background)
+
+	// Notify the styler that the cell is being drawn so any custom
+	// cell styles can be applied to the Renderer.
+	if sh.styler != nil {
+		sh.styler.SetCellStyles(c.row, c.col)
 	}
-	fgColor = c.grid.ctx.Get("fillStyle").String()
 
-	// If the background is white no need to fill the rect.
-	if fgColor != "#ffffff" {
-		c.grid.ctx.Call("fillRect", c.x-c.grid.x, c.y-c.grid.y, c.grid.cellWidth, c.grid.cellHeight)
+	// If the background is white no need to fill the rect, unless the
+	// cell is merged and has to cover the grid lines of the merge.
+	if !isWhite(r.FillStyle()) || merged {
+		r.FillRect(x, y, w, h)
 
 		// TODO: the default grid borders are lightgray consider making a setting and apply
 		// the strokeStyle setting at the createBackGround call.
-		c.grid.ctx.Set("strokeStyle", "lightgray")
-		c.grid.ctx.Call("strokeRect", c.x-c.grid.x, c.y-c.grid.y, c.grid.cellWidth, c.grid.cellHeight)
+		r.SetStrokeStyle("lightgray")
+		r.StrokeRect(x, y, w, h)
 	}
-	str := c.value
-	if c != c.grid.editCell {
-		for width := c.grid.cellWidth + 1; width > c.grid.cellWidth; {
-			tm := c.grid.ctx.Call("measureText", str)
-			width = tm.Get("width").Int()
-			if width > c.grid.cellWidth {
-				str = str[:len(str)-1]
-			}
-		}
+	r.SetFillStyle(style.Color)
+
+	// Notify the styler that the cell is being drawn so any custom
+	// font styles can be applied to the Renderer.
+	if sh.styler != nil {
+		sh.styler.SetCellFontStyles(c.row, c.col)
 	}
-	fontColor := "black"
-	c.grid.ctx.Set("fillStyle", fontColor)
+	sh.drawText(r, c, x, y, w, h, style)
+}
 
-	// Notify the container that the cell is being drawn so any custom 
-	// font styles can be applied to the canvas ctx.
-	if c.grid.container != nil {
-		c.grid.container.SetCellFontStyles(c.row, c.col)
+// Truncate text to fit a width with the font of the Renderer. Text
+// that doesn't fit ends with an ellipsis. The text is cut between
+// grapheme clusters, the longest prefix that fits is found with a
+// binary search over the measured widths.
+func fitText(r Renderer, s string, w int) string {
+	if r.MeasureText(s) <= float64(w) {
+		return s
+	}
+	bounds := []int{0}
+	for i := 0; i < len(s); {
+		i = nextGrapheme(s, i)
+		bounds = append(bounds, i)
 	}
-	c.grid.ctx.Call("fillText", str, c.x-c.grid.x, c.y-c.grid.y+15)
+	lo, hi := 0, len(bounds)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if r.MeasureText(s[:bounds[mid]]+ellipsis) <= float64(w) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	if lo == 0 && r.MeasureText(ellipsis) > float64(w) {
+		return ""
+	}
+	return s[:bounds[lo]] + ellipsis
 }
+
+const ellipsis = "…"
@@ -0,0 +1,305 @@
+package grid
+
+import (
+	"html"
+	"strings"
+)
+
+// The displayed text of the cells of a range, by row.
+func (sh *Sheet) rangeText(r Range) [][]string {
+	return sh.rangeCells(r, (*cell).text)
+}
+
+// The raw values, including formulas, of the cells of a range, by row.
+func (sh *Sheet) rangeValues(r Range) [][]string {
+	return sh.rangeCells(r, func(c *cell) string { return c.value })
+}
+
+func (sh *Sheet) rangeCells(r Range, text func(c *cell) string) [][]string {
+	rows := [][]string{}
+	for row := r.From.Row; row <= r.To.Row; row++ {
+		cells := []string{}
+		for col := r.From.Col; col <= r.To.Col; col++ {
+			s := ""
+			if c, ok := sh.data[Address{row, col}]; ok {
+				s = text(c)
+			}
+			cells = append(cells, s)
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+// The attribute marking the html tables copied from a grid.
+const copiedAttr = "data-grid-copy"
+
+// Check if clipboard html is a table copied from a grid, whose text
+// has the raw values of the cells.
+func copiedHTML(s string) bool {
+	return indexFold(s, "<table "+copiedAttr) >= 0
+}
+
+// Paste rows of cell values with the first cell at the active cell as
+// a single undoable step. The pasted cells are selected. Returns false
+// if nothing is selected.
+func (sh *Sheet) pasteRows(rows [][]string) bool {
+	if sh.selection.empty() {
+		return false
+	}
+	sh.selection.setRange(sh.setRows(sh.selection.active, rows))
+	return true
+}
+
+// Set rows of cell values with the first cell at from as a single
+// undoable step. Returns the range covered by the rows.
+func (sh *Sheet) setRows(from Address, rows [][]string) Range {
+	values := map[Address]string{}
+	to := from
+	for i, cells := range rows {
+		for j, s := range cells {
+			a := Address{from.Row + i, from.Col + j}
+			if a.Col > to.Col {
+				to.Col = a.Col
+			}
+			to.Row = a.Row
+			if _, ok := sh.data[a]; ok || s != "" {
+				values[a] = s
+			}
+		}
+	}
+	sh.setValues(values)
+	return Range{from, to}
+}
+
+// Format rows of cells as tab separated text. Cells containing tabs,
+// line breaks or quotes are quoted the same as spreadsheets do.
+func toTSV(rows [][]string) string {
+	b := strings.Builder{}
+	for i, cells := range rows {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for j, s := range cells {
+			if j > 0 {
+				b.WriteString("\t")
+			}
+			if strings.ContainsAny(s, "\t\r\n\"") {
+				s = `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+			}
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
+
+// Parse tab separated text into rows of cells. Quoted cells may contain
+// tabs, line breaks and doubled quotes. A trailing line break doesn't
+// start a new row.
+func parseTSV(s string) [][]string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	rows := [][]string{}
+	cells := []string{}
+	for i := 0; ; {
+		cell := ""
+		if i < len(s) && s[i] == '"' {
+			// A quoted cell ends at a quote not followed by another.
+			b := strings.Builder{}
+			j := i + 1
+			for j < len(s) {
+				if s[j] == '"' {
+					if j+1 < len(s) && s[j+1] == '"' {
+						b.WriteByte('"')
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[j])
+				j++
+			}
+			if j < len(s) && (j+1 == len(s) || s[j+1] == '\t' || s[j+1] == '\n') {
+				cell = b.String()
+				i = j + 1
+			} else {
+				// Not a quoted cell, the quote is part of the text.
+				end := strings.IndexAny(s[i:], "\t\n")
+				if end < 0 {
+					end = len(s) - i
+				}
+				cell = s[i : i+end]
+				i += end
+			}
+		} else {
+			end := strings.IndexAny(s[i:], "\t\n")
+			if end < 0 {
+				end = len(s) - i
+			}
+			cell = s[i : i+end]
+			i += end
+		}
+		cells = append(cells, cell)
+		if i >= len(s) {
+			rows = append(rows, cells)
+			return rows
+		}
+		if s[i] == '\n' {
+			rows = append(rows, cells)
+			cells = []string{}
+		}
+		i++
+	}
+}
+
+// Format rows of cells as an html table, marked as copied from a grid.
+func toHTML(rows [][]string) string {
+	b := strings.Builder{}
+	b.WriteString("<table " + copiedAttr + ">")
+	for _, cells := range rows {
+		b.WriteString("<tr>")
+		for _, s := range cells {
+			b.WriteString("<td>")
+			b.WriteString(strings.ReplaceAll(html.EscapeString(s), "\n", "<br>"))
+			b.WriteString("</td>")
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</table>")
+	return b.String()
+}
+
+// Parse the first table of an html fragment, such as the clipboard
+// contents of Excel or Google Sheets, into rows of cells. White space
+// is collapsed as a browser would, <br> tags are line breaks and
+// colspan attributes leave empty cells. Returns nil if there is no
+// table.
+func parseHTMLTable(s string) [][]string {
+	start := indexFold(s, "<table")
+	if start < 0 {
+		return nil
+	}
+	s = s[start:]
+	rows := [][]string{}
+	var cells []string
+	var text *strings.Builder
+	span := 1
+	endCell := func() {
+		if text == nil {
+			return
+		}
+		cell := strings.Join(strings.Fields(html.UnescapeString(text.String())), " ")
+		cell = strings.ReplaceAll(cell, " \x00", "\x00")
+		cell = strings.ReplaceAll(cell, "\x00 ", "\x00")
+		cell = strings.ReplaceAll(cell, "\x00", "\n")
+		cells = append(cells, cell)
+		for ; span > 1; span-- {
+			cells = append(cells, "")
+		}
+		text = nil
+	}
+	endRow := func() {
+		endCell()
+		if cells != nil {
+			rows = append(rows, cells)
+		}
+		cells = nil
+	}
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			j := strings.IndexByte(s[i:], '<')
+			if j < 0 {
+				j = len(s) - i
+			}
+			if text != nil {
+				text.WriteString(s[i : i+j])
+			}
+			i += j
+			continue
+		}
+		if strings.HasPrefix(s[i:], "<!--") {
+			j := strings.Index(s[i:], "-->")
+			if j < 0 {
+				break
+			}
+			i += j + 3
+			continue
+		}
+		j := strings.IndexByte(s[i:], '>')
+		if j < 0 {
+			break
+		}
+		tag := s[i+1 : i+j]
+		i += j + 1
+		fields := strings.Fields(tag)
+		if len(fields) == 0 {
+			// Not a tag, such as an empty <>.
+			continue
+		}
+		name := strings.ToLower(strings.TrimRight(fields[0], "/"))
+		switch name {
+		case "tr":
+			endRow()
+			cells = []string{}
+		case "/tr":
+			endRow()
+		case "td", "th":
+			endCell()
+			if cells == nil {
+				cells = []string{}
+			}
+			text = &strings.Builder{}
+			span = attrInt(tag, "colspan")
+		case "/td", "/th":
+			endCell()
+		case "br":
+			if text != nil {
+				// Marked with a NUL so it survives collapsing.
+				text.WriteString("\x00")
+			}
+		case "style", "script":
+			k := indexFold(s[i:], "</"+name)
+			if k < 0 {
+				i = len(s)
+			} else {
+				i += k
+			}
+		case "/table":
+			endRow()
+			return rows
+		}
+	}
+	endRow()
+	return rows
+}
+
+// The index of the first case insensitive instance of substr in s.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// The positive integer value of an attribute of an html tag, or 1 if
+// missing.
+func attrInt(tag, name string) int {
+	i := indexFold(tag, name+"=")
+	if i < 0 {
+		return 1
+	}
+	v := strings.TrimLeft(tag[i+len(name)+1:], `"'`)
+	n := 0
+	for k := 0; k < len(v) && v[k] >= '0' && v[k] <= '9'; k++ {
+		n = n*10 + int(v[k]-'0')
+	}
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,51 @@
+package grid
+
+import (
+	"syscall/js"
+)
+
+// Copy the current selected range to the clipboard as tab separated
+// text of the raw values and formulas, and as an html table of the
+// displayed text. Returns false if nothing is selected.
+func (g *grid) copySelection(data js.Value) bool {
+	r, ok := g.selection.current()
+	if !ok {
+		return false
+	}
+	data.Call("setData", "text/plain", toTSV(g.rangeValues(r)))
+	data.Call("setData", "text/html", toHTML(g.rangeText(r)))
+	return true
+}
+
+// Copy the current selected range to the clipboard and clear it.
+func (g *grid) cutSelection(data js.Value) bool {
+	r, ok := g.selection.current()
+	if !ok || !g.copySelection(data) {
+		return false
+	}
+	values := map[Address]string{}
+	for _, a := range g.index.query(r) {
+		values[a] = ""
+	}
+	g.setValues(values)
+	return true
+}
+
+// Paste the clipboard data into the grid with its first cell at the
+// active cell. Html tables are preferred over text since they keep
+// cells containing tabs and line breaks intact, except for tables
+// copied from a grid whose text has the raw values. The pasted cells
+// are selected.
+func (g *grid) paste(data js.Value) bool {
+	var rows [][]string
+	if s := data.Call("getData", "text/html").String(); s != "" && !copiedHTML(s) {
+		rows = parseHTMLTable(s)
+	}
+	if rows == nil {
+		rows = parseTSV(data.Call("getData", "text/plain").String())
+	}
+	if len(rows) == 0 {
+		return false
+	}
+	return g.pasteRows(rows)
+}
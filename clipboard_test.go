@@ -0,0 +1,95 @@
+package grid
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTSV(t *testing.T) {
+	tests := []struct {
+		text string
+		rows [][]string
+	}{
+		{"a\tb\nc\td", [][]string{{"a", "b"}, {"c", "d"}}},
+		{"a\t\n\tb", [][]string{{"a", ""}, {"", "b"}}},
+		{"\"tab\there\"\t\"line\nbreak\"\n\"say \"\"hi\"\"\"", [][]string{{"tab\there", "line\nbreak"}, {`say "hi"`}}},
+	}
+	for _, tt := range tests {
+		if got := toTSV(tt.rows); got != tt.text {
+			t.Errorf("toTSV(%q) = %q, want %q", tt.rows, got, tt.text)
+		}
+		if got := parseTSV(tt.text); !reflect.DeepEqual(got, tt.rows) {
+			t.Errorf("parseTSV(%q) = %q, want %q", tt.text, got, tt.rows)
+		}
+	}
+
+	parse := []struct {
+		text string
+		rows [][]string
+	}{
+		{"", nil},
+		{"a\r\nb\r\n", [][]string{{"a"}, {"b"}}},
+		// A quote that doesn't end the cell is part of the text.
+		{"\"a\"b\tc", [][]string{{`"a"b`, "c"}}},
+		{"5\" tall", [][]string{{`5" tall`}}},
+	}
+	for _, tt := range parse {
+		if got := parseTSV(tt.text); !reflect.DeepEqual(got, tt.rows) {
+			t.Errorf("parseTSV(%q) = %q, want %q", tt.text, got, tt.rows)
+		}
+	}
+}
+
+func TestHTMLTable(t *testing.T) {
+	rows := [][]string{{"a & b", "<c>"}, {"line\nbreak", ""}}
+	want := "<table data-grid-copy><tr><td>a &amp; b</td><td>&lt;c&gt;</td></tr><tr><td>line<br>break</td><td></td></tr></table>"
+	if got := toHTML(rows); got != want {
+		t.Errorf("toHTML = %q, want %q", got, want)
+	}
+	if got := parseHTMLTable(want); !reflect.DeepEqual(got, rows) {
+		t.Errorf("parseHTMLTable(toHTML) = %q, want %q", got, rows)
+	}
+
+	tests := []struct {
+		html string
+		rows [][]string
+	}{
+		{"<p>no table</p>", nil},
+		// Spreadsheet clipboard html with styles, comments and white
+		// space.
+		{`<html><head><style>td { color: red }</style></head><body><!--StartFragment-->
+<TABLE border=1>
+  <TR><TD class="x">  one
+    two </TD><TD colspan="2">span</TD><TD>end</TD></TR>
+  <tr><th>head</th><td>a<br/> b</td></tr>
+</TABLE><!--EndFragment--></body></html>`,
+			[][]string{{"one two", "span", "", "end"}, {"head", "a\nb"}}},
+		{"<table><tr><td>a<>b</td><td><></td></tr></table>", [][]string{{"ab", ""}}},
+		{"<table><tr><td>cut", [][]string{{"cut"}}},
+	}
+	for _, tt := range tests {
+		if got := parseHTMLTable(tt.html); !reflect.DeepEqual(got, tt.rows) {
+			t.Errorf("parseHTMLTable(%q) = %q, want %q", tt.html, got, tt.rows)
+		}
+	}
+}
+
+// Copying keeps the raw values and formulas in the text and the
+// displayed values in the html table.
+func TestCopyValues(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	sh.addData(0, 0, "1.2345")
+	sh.addData(0, 1, "=A1*2")
+	sh.SetRangeFormat(r1("A1:B1"), "$0.00")
+	r := r1("A1:B1")
+	if got := toTSV(sh.rangeValues(r)); got != "1.2345\t=A1*2" {
+		t.Errorf("copied text = %q", got)
+	}
+	html := toHTML(sh.rangeText(r))
+	if !copiedHTML(html) || !copiedHTML("<html><body><TABLE DATA-GRID-COPY>") || copiedHTML("<table>") {
+		t.Errorf("copied html isn't recognized")
+	}
+	if got := parseHTMLTable(html); !reflect.DeepEqual(got, [][]string{{"$1.23", "$2.47"}}) {
+		t.Errorf("copied html = %q", got)
+	}
+}
@@ -0,0 +1,233 @@
+package grid
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The options of ImportCSV and ExportCSV.
+type CSVOptions struct {
+	// The field delimiter, a comma if zero.
+	Delimiter rune
+	// The text encoding: "utf-8" (the default), "utf-16le", "utf-16be",
+	// "utf-16" (little endian unless there is a byte order mark),
+	// "iso-8859-1" or "windows-1252". A UTF-16 byte order mark in
+	// imported text is followed when no encoding is set.
+	Encoding string
+	// Import the first row as the column header labels and export the
+	// column header labels as the first row.
+	Header bool
+	// Start exported UTF-8 or UTF-16 text with a byte order mark, which
+	// Excel needs to recognize UTF-8.
+	BOM bool
+}
+
+func (o CSVOptions) delimiter() rune {
+	if o.Delimiter == 0 {
+		return ','
+	}
+	return o.Delimiter
+}
+
+// Read CSV text into the cells with the first field at anchor as a
+// single undoable step, including the header labels. Fields are quoted
+// as in RFC 4180, rows may have different numbers of fields and empty
+// fields clear their cells. Returns the range of the imported cells.
+func (sh *Sheet) ImportCSV(r io.Reader, anchor Address, opts CSVOptions) (Range, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Range{}, err
+	}
+	text, err := decodeText(b, opts.Encoding)
+	if err != nil {
+		return Range{}, err
+	}
+	cr := csv.NewReader(strings.NewReader(text))
+	cr.Comma = opts.delimiter()
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return Range{}, err
+	}
+	sh.history.begin()
+	defer sh.history.end()
+	if opts.Header && len(rows) > 0 {
+		for i, label := range rows[0] {
+			col := anchor.Col + i
+			if old := sh.colHeaders[col]; old != label {
+				sh.history.add(headerEdit{false, col, old, label})
+				sh.SetColumnHeader(col, label)
+			}
+		}
+		rows = rows[1:]
+	}
+	if len(rows) == 0 {
+		return Range{anchor, anchor}, nil
+	}
+	return sh.setRows(anchor, rows), nil
+}
+
+// Write the displayed text of the cells of a range as CSV with CRLF
+// line breaks, also inside the fields. Fields containing the delimiter,
+// quotes or line breaks are quoted as in RFC 4180.
+func (sh *Sheet) ExportCSV(w io.Writer, r Range, opts CSVOptions) error {
+	r = NewRange(r.From, r.To)
+	rows := sh.rangeText(r)
+	if opts.Header {
+		labels := []string{}
+		for col := r.From.Col; col <= r.To.Col; col++ {
+			labels = append(labels, sh.columnHeader(col))
+		}
+		rows = append([][]string{labels}, rows...)
+	}
+	var b bytes.Buffer
+	cw := csv.NewWriter(&b)
+	cw.Comma = opts.delimiter()
+	cw.UseCRLF = true
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	out, err := encodeText(b.String(), opts.Encoding, opts.BOM)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// The range of the populated cells, from A1 to the bottom right value.
+func (sh *Sheet) DataRange() Range {
+	return Range{Address{}, sh.lastDataCell(0, true)}
+}
+
+// The canonical name of an encoding, or an error if it isn't supported.
+func encodingName(enc string) (string, error) {
+	switch strings.ToLower(enc) {
+	case "", "utf-8", "utf8":
+		return "utf-8", nil
+	case "utf-16", "utf-16le", "utf-16be":
+		return strings.ToLower(enc), nil
+	case "iso-8859-1", "latin1":
+		return "iso-8859-1", nil
+	case "windows-1252", "cp1252":
+		return "windows-1252", nil
+	}
+	return "", fmt.Errorf("unsupported encoding %q", enc)
+}
+
+var errOddUTF16 = errors.New("utf-16 text has an odd number of bytes")
+
+// Decode text in an encoding to UTF-8, dropping a byte order mark.
+// Invalid UTF-8 is replaced by U+FFFD.
+func decodeText(b []byte, enc string) (string, error) {
+	name, err := encodingName(enc)
+	if err != nil {
+		return "", err
+	}
+	if name == "utf-8" && enc == "" || name == "utf-16" {
+		switch {
+		case bytes.HasPrefix(b, []byte{0xff, 0xfe}):
+			name = "utf-16le"
+		case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+			name = "utf-16be"
+		case name == "utf-16":
+			name = "utf-16le"
+		}
+	}
+	switch name {
+	case "utf-16le", "utf-16be":
+		if len(b)%2 != 0 {
+			return "", errOddUTF16
+		}
+		u := make([]uint16, len(b)/2)
+		for i := range u {
+			if name == "utf-16le" {
+				u[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+			} else {
+				u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+			}
+		}
+		if len(u) > 0 && u[0] == 0xfeff {
+			u = u[1:]
+		}
+		return string(utf16.Decode(u)), nil
+	case "iso-8859-1", "windows-1252":
+		rs := make([]rune, len(b))
+		for i, c := range b {
+			rs[i] = rune(c)
+			if name == "windows-1252" && c >= 0x80 && c < 0xa0 {
+				rs[i] = cp1252[c-0x80]
+			}
+		}
+		return string(rs), nil
+	}
+	b = bytes.TrimPrefix(b, []byte{0xef, 0xbb, 0xbf})
+	return strings.ToValidUTF8(string(b), "\ufffd"), nil
+}
+
+// Encode UTF-8 text in an encoding. Characters the encoding doesn't
+// have are written as "?".
+func encodeText(s, enc string, bom bool) ([]byte, error) {
+	name, err := encodingName(enc)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	switch name {
+	case "utf-16", "utf-16le", "utf-16be":
+		u := utf16.Encode([]rune(s))
+		if bom {
+			u = append([]uint16{0xfeff}, u...)
+		}
+		for _, c := range u {
+			if name == "utf-16be" {
+				b.Write([]byte{byte(c >> 8), byte(c)})
+			} else {
+				b.Write([]byte{byte(c), byte(c >> 8)})
+			}
+		}
+	case "iso-8859-1", "windows-1252":
+		for _, r := range s {
+			b.WriteByte(singleByte(r, name == "windows-1252"))
+		}
+	default:
+		if bom {
+			b.WriteString("\ufeff")
+		}
+		b.WriteString(s)
+	}
+	return b.Bytes(), nil
+}
+
+// The byte of a character in ISO-8859-1 or Windows-1252.
+func singleByte(r rune, windows bool) byte {
+	if windows {
+		for i, c := range cp1252 {
+			if c == r && r != utf8.RuneError {
+				return byte(0x80 + i)
+			}
+		}
+		if r >= 0x80 && r < 0xa0 {
+			return '?'
+		}
+	}
+	if r > 0xff {
+		return '?'
+	}
+	return byte(r)
+}
+
+// The characters of Windows-1252 from 0x80 to 0x9f. The unused bytes
+// are U+FFFD.
+var cp1252 = [32]rune{
+	'€', '\ufffd', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\ufffd', 'Ž', '\ufffd',
+	'\ufffd', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\ufffd', 'ž', 'Ÿ',
+}
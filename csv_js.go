@@ -0,0 +1,75 @@
+package grid
+
+import (
+	"bytes"
+	"errors"
+	"syscall/js"
+)
+
+// Create a JavaScript Promise settled by run.
+func newPromise(run func(resolve, reject js.Value)) js.Value {
+	executor := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		run(args[0], args[1])
+		return nil
+	})
+	defer executor.Release()
+	return js.Global().Get("Promise").New(executor)
+}
+
+// Read the bytes of a File or Blob and call done with them.
+func readBlob(blob js.Value, done func(b []byte, err error)) {
+	var then, catch js.Func
+	release := func() {
+		then.Release()
+		catch.Release()
+	}
+	then = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		release()
+		arr := js.Global().Get("Uint8Array").New(args[0])
+		b := make([]byte, arr.Get("length").Int())
+		js.CopyBytesToGo(b, arr)
+		done(b, nil)
+		return nil
+	})
+	catch = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		release()
+		done(nil, errors.New(args[0].Call("toString").String()))
+		return nil
+	})
+	blob.Call("arrayBuffer").Call("then", then, catch)
+}
+
+// Export a range as CSV and let the browser save it as a file with the
+// name.
+func (g *grid) downloadCSV(r Range, name string, opts CSVOptions) error {
+	charset, err := encodingName(opts.Encoding)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := g.ExportCSV(&b, r, opts); err != nil {
+		return err
+	}
+	arr := js.Global().Get("Uint8Array").New(b.Len())
+	js.CopyBytesToJS(arr, b.Bytes())
+	blob := js.Global().Get("Blob").New([]interface{}{arr},
+		map[string]interface{}{"type": "text/csv;charset=" + charset})
+	url := js.Global().Get("URL").Call("createObjectURL", blob)
+	a := CreateElement("a")
+	a.Set("href", url)
+	a.Set("download", name)
+	body := js.Global().Get("document").Get("body")
+	body.Call("appendChild", a)
+	a.Call("click")
+	body.Call("removeChild", a)
+	// Give the browser time to start the download before the url is
+	// released.
+	var revoke js.Func
+	revoke = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		revoke.Release()
+		js.Global().Get("URL").Call("revokeObjectURL", url)
+		return nil
+	})
+	js.Global().Call("setTimeout", revoke, 0)
+	return nil
+}
@@ -0,0 +1,117 @@
+package grid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestImportCSV(t *testing.T) {
+	sh := NewSheet(400, 300, 80, 25)
+	sh.addData(1, 2, "old")
+	sh.history = history{}
+	csv := "Fruit;Note\r\nApples;\"Red; green\"\r\nPears;\"Say \"\"hi\"\"\nthere\"\r\n;\r\nPlums;12;extra\r\n"
+	r, err := sh.ImportCSV(strings.NewReader(csv), a1("B1"), CSVOptions{Delimiter: ';', Header: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != r1("B1:D4") {
+		t.Errorf("range = %v, want B1:D4", r)
+	}
+	if got := sh.columnHeader(1) + "," + sh.columnHeader(2); got != "Fruit,Note" {
+		t.Errorf("headers = %s", got)
+	}
+	for ref, want := range map[string]string{
+		"B1": "Apples", "C1": "Red; green", "B2": "Pears", "C2": "Say \"hi\"\nthere",
+		"B3": "", "C3": "", "B4": "Plums", "C4": "12", "D4": "extra",
+	} {
+		got := ""
+		if c, ok := sh.data[a1(ref)]; ok {
+			got = c.value
+		}
+		if got != want {
+			t.Errorf("%s = %q, want %q", ref, got, want)
+		}
+	}
+	if c := sh.data[a1("C4")]; c.val != NumberVal(12) {
+		t.Errorf("C4 = %v, want the number 12", c.val)
+	}
+	// The import is a single step that also restores the overwritten
+	// cell.
+	sh.Undo()
+	if c := sh.data[a1("C2")]; c == nil || c.value != "old" {
+		t.Errorf("C2 after undo isn't old")
+	}
+	if got := sh.columnHeader(1); got != "B" {
+		t.Errorf("header after undo = %s, want B", got)
+	}
+	if sh.CanUndo() {
+		t.Errorf("import was more than one step")
+	}
+
+	if _, err := sh.ImportCSV(strings.NewReader("a"), Address{}, CSVOptions{Encoding: "ebcdic"}); err == nil {
+		t.Errorf("import with an unknown encoding succeeded")
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	sh := NewSheet(400, 300, 80, 25)
+	sh.addData(0, 0, "Name")
+	sh.addData(0, 1, "Amount")
+	sh.addData(1, 0, "Smith, Jo")
+	sh.addData(1, 1, "1234.5")
+	sh.addData(2, 0, "Line\nbreak")
+	sh.addData(2, 1, "=B2*2")
+	sh.SetColumnFormat(1, "#,##0.00")
+	sh.SetColumnHeader(0, "Who")
+
+	var b bytes.Buffer
+	if err := sh.ExportCSV(&b, sh.DataRange(), CSVOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "Name,Amount\r\n\"Smith, Jo\",\"1,234.50\"\r\n\"Line\r\nbreak\",\"2,469.00\"\r\n"
+	if b.String() != want {
+		t.Errorf("csv = %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := sh.ExportCSV(&b, r1("A2:A1"), CSVOptions{Delimiter: '\t', Header: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Who\r\nName\r\nSmith, Jo\r\n"; b.String() != want {
+		t.Errorf("csv = %q, want %q", b.String(), want)
+	}
+}
+
+func TestCSVEncodings(t *testing.T) {
+	tests := []struct {
+		enc  string
+		bom  bool
+		text string
+		data []byte
+	}{
+		{"", false, "é", []byte("é")},
+		{"utf-8", true, "é", []byte("\ufeffé")},
+		{"utf-16le", true, "é€", []byte{0xff, 0xfe, 0xe9, 0, 0xac, 0x20}},
+		{"utf-16be", false, "é€", []byte{0, 0xe9, 0x20, 0xac}},
+		{"iso-8859-1", false, "é?", []byte{0xe9, '?'}},
+		{"windows-1252", false, "é€", []byte{0xe9, 0x80}},
+	}
+	for _, tt := range tests {
+		got, err := encodeText(tt.text, tt.enc, tt.bom)
+		if err != nil || !bytes.Equal(got, tt.data) {
+			t.Errorf("encodeText(%q, %q) = % x, %v, want % x", tt.text, tt.enc, got, err, tt.data)
+		}
+		text, err := decodeText(tt.data, tt.enc)
+		if err != nil || text != tt.text {
+			t.Errorf("decodeText(% x, %q) = %q, %v, want %q", tt.data, tt.enc, text, err, tt.text)
+		}
+	}
+	// UTF-16 is recognized by its byte order mark.
+	if text, _ := decodeText([]byte{0xfe, 0xff, 0, 'a'}, ""); text != "a" {
+		t.Errorf("decodeText of utf-16be with a bom = %q, want a", text)
+	}
+	if _, err := decodeText([]byte{0, 'a', 0}, "utf-16be"); err == nil {
+		t.Errorf("decodeText of an odd number of utf-16 bytes succeeded")
+	}
+}
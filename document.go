@@ -0,0 +1,148 @@
+package grid
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Sheet as a JSON document. The values, sizes, formats and styles are
+// the ones set on a grid with the js api, for example:
+//
+//	{"cellWidth": 80, "cellHeight": 25,
+//	 "cells": {"A1": "Fruit", "B1": "Amount", "A2": "Apples", "B2": "12.5"},
+//	 "columnWidths": {"A": 120}, "rowHeights": {"1": 30},
+//	 "formats": [{"range": "B2:B9", "format": "#,##0.00"}],
+//	 "styles": [{"range": "A1:B1", "style": {"background": "#eeeeee", "bold": true}}],
+//	 "merges": ["A10:B10"]}
+//
+// The styles are set in order so a later style takes precedence.
+type document struct {
+	CellWidth    int               `json:"cellWidth"`
+	CellHeight   int               `json:"cellHeight"`
+	Cells        map[string]string `json:"cells"`        // values by A1 address
+	ColumnWidths map[string]int    `json:"columnWidths"` // by column name
+	RowHeights   map[string]int    `json:"rowHeights"`   // by row number
+	Formats      []struct {
+		Range  string `json:"range"`
+		Format string `json:"format"`
+	} `json:"formats"`
+	Styles []struct {
+		Range string    `json:"range"`
+		Style jsonStyle `json:"style"`
+	} `json:"styles"`
+	Merges []string `json:"merges"`
+}
+
+// A CellStyle in JSON, the same object the js api takes.
+type jsonStyle struct {
+	Background string `json:"background"`
+	FontFamily string `json:"fontFamily"`
+	FontSize   int    `json:"fontSize"`
+	FontWeight string `json:"fontWeight"`
+	FontStyle  string `json:"fontStyle"`
+	Bold       bool   `json:"bold"`
+	Italic     bool   `json:"italic"`
+	Color      string `json:"color"`
+	Padding    int    `json:"padding"`
+	Alignment  struct {
+		Horizontal string `json:"horizontal"`
+		Vertical   string `json:"vertical"`
+		Wrap       bool   `json:"wrap"`
+	} `json:"alignment"`
+	Borders struct {
+		Top    jsonBorder `json:"top"`
+		Right  jsonBorder `json:"right"`
+		Bottom jsonBorder `json:"bottom"`
+		Left   jsonBorder `json:"left"`
+	} `json:"borders"`
+}
+
+type jsonBorder struct {
+	Color string `json:"color"`
+	Width int    `json:"width"`
+	Dash  string `json:"dash"`
+}
+
+func (s jsonStyle) style() (CellStyle, error) {
+	cs := CellStyle{s.Background, s.FontFamily, s.FontSize, s.FontWeight, s.FontStyle,
+		s.Color, s.Padding, Alignment{}, Borders{}}
+	if s.Bold {
+		cs.FontWeight = "bold"
+	}
+	if s.Italic {
+		cs.FontStyle = "italic"
+	}
+	var ok bool
+	if cs.Alignment.Horizontal, ok = parseHAlign(s.Alignment.Horizontal); !ok {
+		return cs, fmt.Errorf("unknown alignment %q", s.Alignment.Horizontal)
+	}
+	if cs.Alignment.Vertical, ok = parseVAlign(s.Alignment.Vertical); !ok {
+		return cs, fmt.Errorf("unknown alignment %q", s.Alignment.Vertical)
+	}
+	cs.Alignment.Wrap = s.Alignment.Wrap
+	b := s.Borders
+	cs.Borders = Borders{Border(b.Top), Border(b.Right), Border(b.Bottom), Border(b.Left)}
+	return cs, nil
+}
+
+// Read a Sheet from a JSON document. The view-port of the Sheet is
+// empty, it is meant to be drawn with RenderRange, WritePNG or WriteSVG.
+func ReadSheet(r io.Reader) (*Sheet, error) {
+	doc := document{CellWidth: 80, CellHeight: 25}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	sh := NewSheet(0, 0, doc.CellWidth, doc.CellHeight)
+	for ref, v := range doc.Cells {
+		a, ok := ParseAddress(ref)
+		if !ok {
+			return nil, fmt.Errorf("invalid cell address %q", ref)
+		}
+		sh.addData(a.Row, a.Col, v)
+	}
+	for name, w := range doc.ColumnWidths {
+		a, ok := ParseAddress(name + "1")
+		if !ok || ColumnName(a.Col) != strings.ToUpper(name) {
+			return nil, fmt.Errorf("invalid column %q", name)
+		}
+		sh.SetColumnWidth(a.Col, w)
+	}
+	for number, h := range doc.RowHeights {
+		row, err := strconv.Atoi(number)
+		if err != nil || row < 1 {
+			return nil, fmt.Errorf("invalid row %q", number)
+		}
+		sh.SetRowHeight(row-1, h)
+	}
+	for _, f := range doc.Formats {
+		rg, ok := ParseRange(f.Range)
+		if !ok {
+			return nil, fmt.Errorf("invalid range %q", f.Range)
+		}
+		sh.SetRangeFormat(rg, f.Format)
+	}
+	for _, s := range doc.Styles {
+		rg, ok := ParseRange(s.Range)
+		if !ok {
+			return nil, fmt.Errorf("invalid range %q", s.Range)
+		}
+		style, err := s.Style.style()
+		if err != nil {
+			return nil, err
+		}
+		sh.SetRangeStyle(rg, style)
+	}
+	for _, m := range doc.Merges {
+		rg, ok := ParseRange(m)
+		if !ok {
+			return nil, fmt.Errorf("invalid range %q", m)
+		}
+		sh.MergeCells(rg)
+	}
+	// The values are not changes that can be undone.
+	sh.history = history{}
+	return sh, nil
+}
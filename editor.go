@@ -0,0 +1,293 @@
+package grid
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// The state of the in-cell editor. The text is edited separately from
+// the cell value so the value is only changed when the edit is
+// committed.
+type editor struct {
+	text  string
+	caret int  // byte offset of the caret in text
+	mark  int  // the other end of the selected text, the caret if none
+	edit  bool // the arrow keys move the caret instead of committing
+	// Text being composed with an IME. It is shown at the caret but is
+	// not part of the text until the composition ends.
+	composing string
+	// The earlier and undone states of the text, so Ctrl+Z while
+	// editing undoes the typing instead of the committed changes.
+	undo, redo []editState
+}
+
+// The text and selection of the editor.
+type editState struct {
+	text        string
+	caret, mark int
+}
+
+// Start editing text with the caret at the end. In edit mode the arrow
+// keys move the caret, otherwise they commit the edit and move the
+// active cell.
+func (e *editor) start(text string, edit bool) {
+	*e = editor{text: text, caret: len(text), mark: len(text), edit: edit}
+}
+
+func (e *editor) state() editState {
+	return editState{e.text, e.caret, e.mark}
+}
+
+// Go back to the text before the last change, or forward again if redo
+// is true. Returns false if there is no change to go to.
+func (e *editor) undoText(redo bool) bool {
+	from, to := &e.undo, &e.redo
+	if redo {
+		from, to = to, from
+	}
+	n := len(*from)
+	if n == 0 {
+		return false
+	}
+	*to = append(*to, e.state())
+	s := (*from)[n-1]
+	*from = (*from)[:n-1]
+	e.text, e.caret, e.mark = s.text, s.caret, s.mark
+	return true
+}
+
+// The text shown in the cell, including the text being composed.
+func (e *editor) display() string {
+	return e.text[:e.caret] + e.composing + e.text[e.caret:]
+}
+
+// The start and end of the selected text.
+func (e *editor) selection() (int, int) {
+	if e.mark < e.caret {
+		return e.mark, e.caret
+	}
+	return e.caret, e.mark
+}
+
+func (e *editor) selected() string {
+	start, end := e.selection()
+	return e.text[start:end]
+}
+
+// Replace the selected text, or insert at the caret, with s.
+func (e *editor) insert(s string) {
+	start, end := e.selection()
+	if s != "" || start != end {
+		e.undo = append(e.undo, e.state())
+		e.redo = nil
+	}
+	e.text = e.text[:start] + s + e.text[end:]
+	e.caret = start + len(s)
+	e.mark = e.caret
+}
+
+// Delete the selected text, or the character before the caret.
+func (e *editor) deleteBack() {
+	if e.mark == e.caret {
+		e.mark = e.prev(e.caret)
+	}
+	e.insert("")
+}
+
+// Delete the selected text, or the character after the caret.
+func (e *editor) deleteForward() {
+	if e.mark == e.caret {
+		e.mark = e.next(e.caret)
+	}
+	e.insert("")
+}
+
+// Move the caret to i. If extend is true the selected text is extended
+// to i.
+func (e *editor) moveTo(i int, extend bool) {
+	e.caret = i
+	if !extend {
+		e.mark = i
+	}
+}
+
+// The offsets of the characters before and after i. A character is a
+// grapheme cluster so accents and emoji sequences are moved over and
+// deleted as a whole.
+func (e *editor) prev(i int) int {
+	return prevGrapheme(e.text, i)
+}
+
+func (e *editor) next(i int) int {
+	return nextGrapheme(e.text, i)
+}
+
+// Start editing the active cell. If keep is true the editor starts
+// with the cell value in edit mode, otherwise it starts empty so typing
+// replaces the value.
+func (sh *Sheet) startEdit(keep bool) {
+	sh.commitEdit()
+	if sh.selection.empty() {
+		sh.selection.set(Address{0, 0})
+	}
+	a := sh.selection.active
+	c := sh.cellAt(a)
+	c.editing = true
+	sh.editCell = c
+	text := ""
+	if keep {
+		text = c.value
+	}
+	sh.editor.start(text, keep)
+	sh.scrollTo(a)
+}
+
+// Commit the text entered into the edit cell.
+func (sh *Sheet) commitEdit() {
+	ec := sh.editCell
+	if ec == nil {
+		return
+	}
+	ec.editing = false
+	sh.editCell = nil
+	if _, ok := sh.data[Address{ec.row, ec.col}]; !ok && sh.editor.text == "" {
+		return
+	}
+	sh.addData(ec.row, ec.col, sh.editor.text)
+	if sh.listener != nil {
+		sh.listener.AddCellsDone()
+	}
+}
+
+// Stop editing without committing the text entered into the edit cell.
+func (sh *Sheet) cancelEdit() {
+	if sh.editCell == nil {
+		return
+	}
+	sh.editCell.editing = false
+	sh.editCell = nil
+}
+
+// Handle a key while editing. Returns false if the key is not used by
+// the editor.
+func (sh *Sheet) editKey(key string, shift bool) bool {
+	e := &sh.editor
+	switch key {
+	case "Escape":
+		sh.cancelEdit()
+	case "Enter", "Tab", "ArrowUp", "ArrowDown":
+		sh.commitEdit()
+		sh.navigate(key, shift, false)
+	case "ArrowLeft", "ArrowRight":
+		if !e.edit {
+			sh.commitEdit()
+			sh.navigate(key, shift, false)
+		} else if key == "ArrowLeft" {
+			e.moveTo(e.prev(e.caret), shift)
+		} else {
+			e.moveTo(e.next(e.caret), shift)
+		}
+	case "Home":
+		e.moveTo(0, shift)
+	case "End":
+		e.moveTo(len(e.text), shift)
+	case "Backspace":
+		e.deleteBack()
+	case "Delete":
+		e.deleteForward()
+	case "F2":
+		// Switch between moving the caret and moving the active cell.
+		e.edit = !e.edit
+	default:
+		if utf8.RuneCountInString(key) != 1 {
+			return false
+		}
+		e.insert(key)
+	}
+	return true
+}
+
+// Undo, or redo, a change. While editing only the typing in the editor
+// is undone, the edit is not cancelled.
+func (sh *Sheet) undoKey(redo bool) {
+	switch {
+	case sh.editCell != nil:
+		sh.editor.undoText(redo)
+	case redo:
+		sh.Redo()
+	default:
+		sh.Undo()
+	}
+}
+
+// Clear the values of the selected cells as a single undoable step.
+func (sh *Sheet) clearSelected() {
+	values := map[Address]string{}
+	for _, r := range sh.selection.ranges {
+		for _, a := range sh.index.query(r) {
+			if sh.filled(a) {
+				values[a] = ""
+			}
+		}
+	}
+	if len(values) > 0 {
+		sh.setValues(values)
+	}
+}
+
+// Start an IME composition. Composing on a cell that isn't being
+// edited replaces its value, the same as typing.
+func (sh *Sheet) startComposition() {
+	if sh.editCell == nil {
+		sh.startEdit(false)
+	}
+	sh.editor.insert("")
+}
+
+func (sh *Sheet) updateComposition(s string) {
+	sh.editor.composing = s
+}
+
+// End an IME composition by entering the composed text.
+func (sh *Sheet) endComposition(s string) {
+	sh.editor.composing = ""
+	if sh.editCell != nil {
+		sh.editor.insert(s)
+	}
+}
+
+// Insert text pasted while editing. Line breaks are kept.
+func (sh *Sheet) pasteEdit(s string) {
+	sh.editor.insert(strings.ReplaceAll(s, "\r\n", "\n"))
+}
+
+// Draw the caret, the selected text and the underlined composition
+// text of the editor over the edit cell. The cell text is drawn left
+// aligned at x.
+func (sh *Sheet) drawEditor(r Renderer, x, y, h int) {
+	e := &sh.editor
+	text := e.display()
+	width := func(s string) int {
+		return int(r.MeasureText(s))
+	}
+	start, end := e.selection()
+	r.Save()
+	if start != end {
+		sx := x + width(text[:start])
+		r.SetFillStyle("rgba(0, 120, 215, 0.3)")
+		r.FillRect(sx, y+2, x+width(text[:end])-sx, h-4)
+	}
+	r.SetStrokeStyle("black")
+	r.SetLineWidth(1)
+	r.BeginPath()
+	if e.composing != "" {
+		ux := float64(x + width(text[:e.caret]))
+		r.MoveTo(ux, float64(y+h-3))
+		r.LineTo(ux+float64(width(e.composing)), float64(y+h-3))
+	}
+	cx := float64(x+width(text[:e.caret+len(e.composing)])) + 0.5
+	r.MoveTo(cx, float64(y+3))
+	r.LineTo(cx, float64(y+h-3))
+	r.Stroke()
+	r.Restore()
+}
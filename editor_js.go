@@ -0,0 +1,17 @@
+package grid
+
+import (
+	"strconv"
+)
+
+// Move the hidden input over the active cell so the IME candidate
+// window opens next to it.
+func (g *grid) placeInput() {
+	a := g.selection.active
+	x, y := g.toView(g.addressToCoords(a.Row, a.Col))
+	style := g.input.Get("style")
+	style.Set("left", strconv.Itoa(x)+"px")
+	style.Set("top", strconv.Itoa(y)+"px")
+	_, h := g.cellSize(a)
+	style.Set("height", strconv.Itoa(h)+"px")
+}
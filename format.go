@@ -0,0 +1,592 @@
+package grid
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Cell display formats. A format changes the text shown for a value
+// but never the value itself. The format strings follow the usual
+// spreadsheet conventions, for example "#,##0.00", "0%", "$#,##0",
+// "0.00E+00", "yyyy-mm-dd hh:mm" or "[h]:mm" for elapsed time. Up to
+// four sections separated by ";" format positive numbers, negative
+// numbers, zero and text. A value with a format that has an unknown
+// bracketed code, other than a color or condition, shows as it would
+// without a format.
+
+// A part of a format section. Literal parts are copied to the output,
+// code parts are placeholders or date codes.
+type formatPart struct {
+	literal bool
+	text    string
+}
+
+// Split a format string into sections, ignoring separators that are
+// quoted or escaped.
+func formatSections(format string) []string {
+	sections := []string{}
+	start := 0
+	quoted := false
+	for i := 0; i < len(format); i++ {
+		switch format[i] {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			i++
+		case ';':
+			if !quoted {
+				sections = append(sections, format[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(sections, format[start:])
+}
+
+// Split a format section into literal and code parts. Returns false if
+// the section has a bracketed code that isn't supported.
+func formatParts(section string) ([]formatPart, bool) {
+	parts := []formatPart{}
+	ok := true
+	add := func(literal bool, text string) {
+		if n := len(parts); n > 0 && parts[n-1].literal == literal {
+			parts[n-1].text += text
+			return
+		}
+		parts = append(parts, formatPart{literal, text})
+	}
+	for i := 0; i < len(section); i++ {
+		c := section[i]
+		upper := strings.ToUpper(section[i:])
+		switch {
+		case strings.HasPrefix(upper, "AM/PM"):
+			add(false, section[i:i+5])
+			i += 4
+			continue
+		case strings.HasPrefix(upper, "A/P"):
+			add(false, section[i:i+3])
+			i += 2
+			continue
+		}
+		switch c {
+		case '"':
+			j := strings.IndexByte(section[i+1:], '"')
+			if j < 0 {
+				j = len(section) - i - 1
+			}
+			add(true, section[i+1:i+1+j])
+			i += j + 1
+		case '\\':
+			if i+1 < len(section) {
+				add(true, section[i+1:i+2])
+				i++
+			}
+		case '_':
+			// Space the width of the next character.
+			add(true, " ")
+			i++
+		case '*':
+			// Fill characters are not supported.
+			i++
+		case '[':
+			// A bracket that isn't closed runs to the end of the
+			// section.
+			end := len(section)
+			if j := strings.IndexByte(section[i:], ']'); j >= 0 {
+				end = i + j
+			}
+			tag := section[i+1 : end]
+			switch {
+			case strings.HasPrefix(tag, "$"):
+				// [$€-407] style currency symbols.
+				if k := strings.IndexByte(tag, '-'); k >= 0 {
+					tag = tag[:k]
+				}
+				add(true, tag[1:])
+			case isElapsedCode(tag):
+				add(false, "["+tag+"]")
+			case tag != "" && strings.IndexByte("<>=", tag[0]) >= 0, isColorCode(tag):
+				// Conditions and colors are ignored.
+			default:
+				ok = false
+			}
+			i = end
+		case '$', '-', '+', '(', ')', ':', '/', ' ', '%', '&', '\'', '~', '{', '}', '<', '>', '=', '!', '^':
+			add(true, string(c))
+		default:
+			if c >= 0x80 {
+				// Copy multi-byte characters such as currency
+				// symbols whole.
+				j := i + 1
+				for j < len(section) && section[j]&0xc0 == 0x80 {
+					j++
+				}
+				add(true, section[i:j])
+				i = j - 1
+				continue
+			}
+			add(false, string(c))
+		}
+	}
+	return parts, ok
+}
+
+// Check if a bracketed format code is [h], [m] or [s], the elapsed
+// hours, minutes or seconds, with the letter repeated for padding.
+func isElapsedCode(tag string) bool {
+	tag = strings.ToLower(tag)
+	return tag != "" && strings.IndexByte("hms", tag[0]) >= 0 &&
+		strings.Trim(tag, tag[:1]) == ""
+}
+
+// The color names of bracketed format codes such as [Red].
+var formatColors = []string{"black", "blue", "cyan", "green", "magenta", "red", "white", "yellow"}
+
+// Check if a bracketed format code is a color, a name or [ColorN].
+func isColorCode(tag string) bool {
+	tag = strings.ToLower(tag)
+	for _, c := range formatColors {
+		if tag == c {
+			return true
+		}
+	}
+	if n := strings.TrimPrefix(tag, "color"); n != tag {
+		_, err := strconv.Atoi(n)
+		return err == nil
+	}
+	return false
+}
+
+// Check if a format section formats dates and times.
+func isDateFormat(parts []formatPart) bool {
+	for _, p := range parts {
+		if !p.literal && strings.ContainsAny(p.text, "yYmMdDhHsS") {
+			return true
+		}
+	}
+	return false
+}
+
+// Format a value with a format string for display in a cell.
+func formatValue(v Value, format string) string {
+	if format == "" || strings.EqualFold(format, "General") {
+		return v.String()
+	}
+	sections := formatSections(format)
+	switch v.kind {
+	case NumberKind, DateKind:
+		n := v.num
+		section := sections[0]
+		negative := n < 0
+		if n < 0 && len(sections) > 1 {
+			// The negative section shows the sign itself.
+			section = sections[1]
+			n = -n
+			negative = false
+		} else if n == 0 && len(sections) > 2 {
+			section = sections[2]
+		}
+		parts, ok := formatParts(section)
+		if !ok || strings.Contains(section, "@") {
+			return v.String()
+		}
+		if isDateFormat(parts) {
+			return formatDate(n, parts)
+		}
+		if negative {
+			n = -n
+		}
+		s := formatNumber(n, parts)
+		if negative && strings.ContainsAny(s, "123456789") {
+			s = "-" + s
+		}
+		return s
+	case TextKind:
+		section := ""
+		if len(sections) > 3 {
+			section = sections[3]
+		} else if strings.Contains(sections[0], "@") {
+			section = sections[0]
+		}
+		if section == "" {
+			return v.String()
+		}
+		parts, ok := formatParts(section)
+		if !ok {
+			return v.String()
+		}
+		s := ""
+		for _, p := range parts {
+			if p.literal {
+				s += p.text
+			} else {
+				s += strings.Replace(p.text, "@", v.str, -1)
+			}
+		}
+		return s
+	}
+	return v.String()
+}
+
+// Format a non negative number with a number format section.
+func formatNumber(n float64, parts []formatPart) string {
+	// Find the placeholder pattern, the literal text around it is
+	// the prefix and suffix.
+	prefix, pattern, suffix := "", "", ""
+	for _, p := range parts {
+		switch {
+		case p.literal && pattern == "":
+			prefix += p.text
+		case p.literal:
+			suffix += p.text
+		case suffix == "" && (pattern != "" || strings.ContainsAny(p.text, "0#?.")):
+			pattern += p.text
+		case pattern == "":
+			prefix += p.text
+		default:
+			suffix += p.text
+		}
+	}
+	// A sign after the exponent marker is part of the pattern.
+	if i := strings.IndexAny(pattern, "eE"); i >= 0 && i == len(pattern)-1 && len(suffix) > 0 &&
+		(suffix[0] == '+' || suffix[0] == '-') {
+		j := 1
+		for j < len(suffix) && suffix[j] == '0' {
+			j++
+		}
+		pattern += suffix[:j]
+		suffix = suffix[j:]
+	}
+	for i := strings.Count(prefix+suffix, "%"); i > 0; i-- {
+		n *= 100
+	}
+
+	exponent := ""
+	if i := strings.IndexAny(pattern, "eE"); i >= 0 {
+		exponent = pattern[i+1:]
+		pattern = pattern[:i]
+	}
+	intPattern, fracPattern := pattern, ""
+	dot := strings.IndexByte(pattern, '.')
+	if dot >= 0 {
+		intPattern, fracPattern = pattern[:dot], pattern[dot+1:]
+	}
+	// Trailing commas scale by a thousand each.
+	for strings.HasSuffix(intPattern, ",") {
+		intPattern = intPattern[:len(intPattern)-1]
+		n /= 1000
+	}
+	group := strings.Contains(intPattern, ",")
+	minInt := strings.Count(intPattern, "0")
+	minFrac := strings.Count(fracPattern, "0")
+	maxFrac := minFrac + strings.Count(fracPattern, "#") + strings.Count(fracPattern, "?")
+
+	exp := 0
+	if exponent != "" && n != 0 {
+		exp = int(math.Floor(math.Log10(n)))
+		// Keep the number of integer digits in the pattern.
+		if minInt > 1 {
+			exp -= minInt - 1
+		}
+		n /= math.Pow(10, float64(exp))
+		if r, _ := strconv.ParseFloat(roundDecimal(n, maxFrac), 64); r >= math.Pow(10, math.Max(1, float64(minInt))) {
+			exp++
+			n /= 10
+		}
+	}
+
+	s := roundDecimal(n, maxFrac)
+	intDigits, fracDigits := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intDigits, fracDigits = s[:i], s[i+1:]
+	}
+	for len(fracDigits) > minFrac && fracDigits[len(fracDigits)-1] == '0' {
+		fracDigits = fracDigits[:len(fracDigits)-1]
+	}
+	intDigits = strings.TrimLeft(intDigits, "0")
+	for len(intDigits) < minInt {
+		intDigits = "0" + intDigits
+	}
+	if group {
+		for i := len(intDigits) - 3; i > 0; i -= 3 {
+			intDigits = intDigits[:i] + "," + intDigits[i:]
+		}
+	}
+	s = intDigits
+	if dot >= 0 {
+		s += "." + fracDigits
+	}
+	if exponent != "" {
+		sign := ""
+		if exp < 0 {
+			sign = "-"
+			exp = -exp
+		} else if strings.HasPrefix(exponent, "+") {
+			sign = "+"
+		}
+		digits := strconv.Itoa(exp)
+		for len(digits) < strings.Count(exponent, "0") {
+			digits = "0" + digits
+		}
+		s += "E" + sign + digits
+	}
+	return prefix + s + suffix
+}
+
+// The decimal text of a non negative number rounded to digits decimals,
+// with halves rounded up as spreadsheets do. The shortest text of the
+// number is rounded so 1.005 is 1.01 and not the 1.00 of its binary
+// value.
+func roundDecimal(n float64, digits int) string {
+	s := strconv.FormatFloat(n, 'f', -1, 64)
+	dot := strings.IndexByte(s, '.')
+	if dot < 0 {
+		dot = len(s)
+		s += "."
+	}
+	for len(s)-dot-1 < digits {
+		s += "0"
+	}
+	end := dot + 1 + digits
+	up := end < len(s) && s[end] >= '5'
+	b := []byte("0" + s[:end])
+	for i := len(b) - 1; up && i >= 0; i-- {
+		switch b[i] {
+		case '.':
+		case '9':
+			b[i] = '0'
+		default:
+			b[i]++
+			up = false
+		}
+	}
+	s = strings.TrimSuffix(strings.TrimLeft(string(b), "0"), ".")
+	if s == "" || s[0] == '.' {
+		s = "0" + s
+	}
+	return s
+}
+
+// A date format code such as "yyyy" or "AM/PM".
+type dateCode struct {
+	code  byte // y, m, d, h, n (minute), s, a (am/pm), H, M, S (elapsed) or 0 for literals
+	count int
+	text  string
+}
+
+// Format a date serial number with a date format section.
+func formatDate(n float64, parts []formatPart) string {
+	codes := []dateCode{}
+	ampm, elapsed := false, false
+	for _, p := range parts {
+		if p.literal {
+			codes = append(codes, dateCode{text: p.text})
+			continue
+		}
+		s := p.text
+		for i := 0; i < len(s); {
+			upper := strings.ToUpper(s[i:])
+			switch {
+			case strings.HasPrefix(upper, "AM/PM"):
+				codes = append(codes, dateCode{code: 'a', count: 5})
+				ampm = true
+				i += 5
+				continue
+			case strings.HasPrefix(upper, "A/P"):
+				codes = append(codes, dateCode{code: 'a', count: 3})
+				ampm = true
+				i += 3
+				continue
+			}
+			if s[i] == '[' {
+				// Elapsed time, kept closed by formatParts.
+				j := strings.IndexByte(s[i:], ']')
+				codes = append(codes, dateCode{code: upper[1], count: j - 1})
+				elapsed = true
+				i += j + 1
+				continue
+			}
+			c := upper[0] | 0x20
+			if strings.IndexByte("ymdhs", c) < 0 {
+				codes = append(codes, dateCode{text: s[i : i+1]})
+				i++
+				continue
+			}
+			j := i
+			for j < len(s) && s[j]|0x20 == c {
+				j++
+			}
+			codes = append(codes, dateCode{code: c, count: j - i})
+			i = j
+		}
+	}
+	// Minutes are an m code after an hour or before a second code.
+	last := byte(0)
+	for i := range codes {
+		if codes[i].code == 0 {
+			continue
+		}
+		if codes[i].code == 'm' && codes[i].count <= 2 {
+			if last == 'h' || last == 'H' {
+				codes[i].code = 'n'
+			} else {
+				for _, next := range codes[i+1:] {
+					if next.code != 0 {
+						if next.code == 's' || next.code == 'S' {
+							codes[i].code = 'n'
+						}
+						break
+					}
+				}
+			}
+		}
+		last = codes[i].code
+	}
+
+	// Elapsed times count from zero, a negative time is shown with a
+	// minus sign.
+	sign := ""
+	if elapsed && n < 0 {
+		sign = "-"
+		n = -n
+	}
+	t := serialTime(n)
+	ms := math.Round(n * 24 * 60 * 60 * 1000)
+	pad := func(v, count int) string {
+		s := strconv.Itoa(v)
+		if count >= 2 && len(s) < 2 {
+			s = "0" + s
+		}
+		return s
+	}
+	s := ""
+	for _, c := range codes {
+		switch c.code {
+		case 0:
+			s += c.text
+		case 'y':
+			if c.count <= 2 {
+				s += pad(t.Year()%100, 2)
+			} else {
+				s += strconv.Itoa(t.Year())
+			}
+		case 'm':
+			switch c.count {
+			case 1, 2:
+				s += pad(int(t.Month()), c.count)
+			case 3:
+				s += t.Month().String()[:3]
+			case 5:
+				s += t.Month().String()[:1]
+			default:
+				s += t.Month().String()
+			}
+		case 'd':
+			switch c.count {
+			case 1, 2:
+				s += pad(t.Day(), c.count)
+			case 3:
+				s += t.Weekday().String()[:3]
+			default:
+				s += t.Weekday().String()
+			}
+		case 'h':
+			h := t.Hour()
+			if ampm {
+				h %= 12
+				if h == 0 {
+					h = 12
+				}
+			}
+			s += pad(h, c.count)
+		case 'H':
+			s += sign + pad(int(ms/(60*60*1000)), c.count)
+		case 'M':
+			s += sign + pad(int(ms/(60*1000)), c.count)
+		case 'S':
+			s += sign + pad(int(ms/1000), c.count)
+		case 'n':
+			s += pad(t.Minute(), c.count)
+		case 's':
+			s += pad(t.Second(), c.count)
+		case 'a':
+			am, pm := "AM", "PM"
+			if c.count == 3 {
+				am, pm = "A", "P"
+			}
+			if t.Hour() < 12 {
+				s += am
+			} else {
+				s += pm
+			}
+		}
+	}
+	return s
+}
+
+// The display formats of the grid. A cell format takes precedence
+// over a range format, and a range format over a column format.
+// Setting a range format replaces the cell formats inside the range.
+type formats struct {
+	cells   map[Address]string
+	ranges  []rangeFormat
+	columns map[int]string
+}
+
+type rangeFormat struct {
+	r      Range
+	format string
+}
+
+func newFormats() formats {
+	return formats{map[Address]string{}, []rangeFormat{}, map[int]string{}}
+}
+
+func (f *formats) copy() formats {
+	c := newFormats()
+	for a, format := range f.cells {
+		c.cells[a] = format
+	}
+	c.ranges = append(c.ranges, f.ranges...)
+	c.columns = copyLabels(f.columns)
+	return c
+}
+
+// The format of the cell at a. An empty format is the general format.
+func (f *formats) get(a Address) string {
+	if format, ok := f.cells[a]; ok {
+		return format
+	}
+	// Later ranges override earlier ones.
+	for i := len(f.ranges) - 1; i >= 0; i-- {
+		if f.ranges[i].r.Contains(a) {
+			return f.ranges[i].format
+		}
+	}
+	return f.columns[a.Col]
+}
+
+func (f *formats) setCell(a Address, format string) {
+	f.cells[a] = format
+}
+
+func (f *formats) setColumn(col int, format string) {
+	f.columns[col] = format
+}
+
+func (f *formats) setRange(r Range, format string) {
+	for a := range f.cells {
+		if r.Contains(a) {
+			delete(f.cells, a)
+		}
+	}
+	// Drop ranges the new range completely covers.
+	ranges := f.ranges[:0]
+	for _, rf := range f.ranges {
+		if !(r.Contains(rf.r.From) && r.Contains(rf.r.To)) {
+			ranges = append(ranges, rf)
+		}
+	}
+	f.ranges = append(ranges, rangeFormat{r, format})
+}
@@ -0,0 +1,70 @@
+package grid
+
+import (
+	"testing"
+)
+
+func TestFormatValue(t *testing.T) {
+	date := ParseValue("2024-03-05 14:07:09")
+	tests := []struct {
+		v      Value
+		format string
+		want   string
+	}{
+		{NumberVal(1234.5), "", "1234.5"},
+		{NumberVal(1234.5), "General", "1234.5"},
+		{NumberVal(1234.567), "#,##0.00", "1,234.57"},
+		{NumberVal(-1234567.891), "#,##0.00", "-1,234,567.89"},
+		{NumberVal(0.5), "#,##0.00", "0.50"},
+		{NumberVal(0.256), "0%", "26%"},
+		{NumberVal(0.256), "0.0%", "25.6%"},
+		{NumberVal(1234.4), "$#,##0", "$1,234"},
+		{NumberVal(1234.5), "$#,##0", "$1,235"},
+		{NumberVal(0.5), "0", "1"},
+		{NumberVal(1.005), "0.00", "1.01"},
+		{NumberVal(9.995), "0.00", "10.00"},
+		{NumberVal(0.125), "0.0%", "12.5%"},
+		{NumberVal(0.0005), "0.000", "0.001"},
+		{NumberVal(-2.5), "0", "-3"},
+		{NumberVal(-1234.5), "$#,##0.00;($#,##0.00)", "($1,234.50)"},
+		{NumberVal(0), "0.00;-0.00;\"zero\"", "zero"},
+		{NumberVal(-0.001), "0.00", "0.00"},
+		{NumberVal(12345), "0.00E+00", "1.23E+04"},
+		{NumberVal(5), "000", "005"},
+		{NumberVal(1234.5), "[$€-407]#,##0.00", "€1,234.50"},
+		{NumberVal(3), "0 \"items\"", "3 items"},
+		{date, "yyyy-mm-dd", "2024-03-05"},
+		{date, "dd/mm/yy", "05/03/24"},
+		{date, "d mmm yyyy", "5 Mar 2024"},
+		{date, "yyyy-mm-dd hh:mm", "2024-03-05 14:07"},
+		{date, "h:mm:ss AM/PM", "2:07:09 PM"},
+		{NumberVal(45356), "yyyy-mm-dd", "2024-03-05"},
+		{ParseValue("2300-01-02 06:30"), "yyyy-mm-dd hh:mm", "2300-01-02 06:30"},
+		{ParseValue("1500-01-02"), "d mmm yyyy", "2 Jan 1500"},
+		{NumberVal(2958465.75), "yyyy-mm-dd hh:mm", "9999-12-31 18:00"},
+		{NumberVal(-0.25), "yyyy-mm-dd hh:mm", "1899-12-29 18:00"},
+		{TextVal("abc"), "#,##0.00", "abc"},
+		{TextVal("abc"), "0;0;0;\"<\"@\">\"", "<abc>"},
+		{BoolVal(true), "0.00", "TRUE"},
+		{NumberVal(1), "0[", "1"},
+		{NumberVal(1.5), "[h]:mm", "36:00"},
+		{NumberVal(1.5), "[h]:mm:ss", "36:00:00"},
+		{NumberVal(0.0625), "[mm]:ss", "90:00"},
+		{NumberVal(0.0000115741), "[s]", "1"},
+		{NumberVal(2.00069444), "[hh]:mm", "48:01"},
+		{NumberVal(-0.3), "[h]:mm", "-7:12"},
+		{NumberVal(-1234.5), "[Red]0.0", "-1234.5"},
+		{NumberVal(3), "[Color10]0;[>100]0", "3"},
+		{NumberVal(1.5), "[hh:mm]", "1.5"},
+		{NumberVal(1.5), "[DBNum1]0", "1.5"},
+		{TextVal("x"), "0;0;0;[Blue]@", "x"},
+		{TextVal("x"), "0;0;0;[Bold]@", "x"},
+		{NumberVal(1), "0.0[$€", "1.0€"},
+		{NumberVal(1), "[", "1"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.v, tt.format); got != tt.want {
+			t.Errorf("formatValue(%v, %q) = %q, want %q", tt.v, tt.format, got, tt.want)
+		}
+	}
+}
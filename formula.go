@@ -0,0 +1,717 @@
+package grid
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Check if a cell value is a formula.
+func isFormula(s string) bool {
+	return len(s) > 1 && s[0] == '='
+}
+
+// A range of cells. From is the top left and To the bottom right.
+type Range struct {
+	From Address
+	To   Address
+}
+
+// Create a Range from two corner addresses in any order.
+func NewRange(a, b Address) Range {
+	if a.Row > b.Row {
+		a.Row, b.Row = b.Row, a.Row
+	}
+	if a.Col > b.Col {
+		a.Col, b.Col = b.Col, a.Col
+	}
+	return Range{a, b}
+}
+
+// Check if the address is inside the range.
+func (r Range) Contains(a Address) bool {
+	return a.Row >= r.From.Row && a.Row <= r.To.Row &&
+		a.Col >= r.From.Col && a.Col <= r.To.Col
+}
+
+// The A1 notation of the range. A single cell range is the address of
+// the cell.
+func (r Range) String() string {
+	if r.From == r.To {
+		return r.From.String()
+	}
+	return r.From.String() + ":" + r.To.String()
+}
+
+// Convert a zero based column to its letter name: 0 -> A, 26 -> AA.
+func ColumnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// The A1 style name of the address.
+func (a Address) String() string {
+	return ColumnName(a.Col) + strconv.Itoa(a.Row+1)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Parse an A1 style reference such as B3 or $B$3.
+func ParseAddress(s string) (Address, bool) {
+	var a Address
+	i := 0
+	if i < len(s) && s[i] == '$' {
+		i++
+	}
+	col := 0
+	start := i
+	for ; i < len(s) && isLetter(s[i]); i++ {
+		col = col*26 + int(s[i]|0x20-'a') + 1
+		if col > 1<<20 {
+			return a, false
+		}
+	}
+	if i == start {
+		return a, false
+	}
+	if i < len(s) && s[i] == '$' {
+		i++
+	}
+	// The rows are capped like the columns so pixel offsets can't
+	// overflow.
+	row, err := strconv.Atoi(s[i:])
+	if err != nil || row < 1 || row > 1<<30 || s[i] == '+' {
+		return a, false
+	}
+	a.Row = row - 1
+	a.Col = col - 1
+	return a, true
+}
+
+// Parse an A1:C4 style range. A single address is a one cell range.
+func ParseRange(s string) (Range, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 2 {
+		return Range{}, false
+	}
+	a, ok := ParseAddress(parts[0])
+	if !ok {
+		return Range{}, false
+	}
+	b := a
+	if len(parts) == 2 {
+		if b, ok = ParseAddress(parts[1]); !ok {
+			return Range{}, false
+		}
+	}
+	return NewRange(a, b), true
+}
+
+// Formula token types.
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokColon
+	tokError
+)
+
+type token struct {
+	typ  tokenType
+	text string
+}
+
+// Split the formula source into tokens.
+func tokenize(src string) ([]token, bool) {
+	tokens := []token{}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				k := j + 1
+				if k < len(src) && (src[k] == '+' || src[k] == '-') {
+					k++
+				}
+				if k < len(src) && src[k] >= '0' && src[k] <= '9' {
+					for j = k; j < len(src) && src[j] >= '0' && src[j] <= '9'; j++ {
+					}
+				}
+			}
+			tokens = append(tokens, token{tokNumber, src[i:j]})
+			i = j
+		case c == '"':
+			var s strings.Builder
+			j := i + 1
+			for {
+				if j >= len(src) {
+					return nil, false
+				}
+				if src[j] == '"' {
+					// A doubled quote is an escaped quote.
+					if j+1 < len(src) && src[j+1] == '"' {
+						s.WriteByte('"')
+						j += 2
+						continue
+					}
+					break
+				}
+				s.WriteByte(src[j])
+				j++
+			}
+			tokens = append(tokens, token{tokString, s.String()})
+			i = j + 1
+		case c == '$' || c == '_' || isLetter(c):
+			j := i
+			for j < len(src) && (src[j] == '$' || src[j] == '_' ||
+				isLetter(src[j]) || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, src[i:j]})
+			i = j
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ","})
+			i++
+		case c == ':':
+			tokens = append(tokens, token{tokColon, ":"})
+			i++
+		case c == '#' && strings.HasPrefix(src[i:], errRef):
+			// A reference to a removed cell.
+			tokens = append(tokens, token{tokError, errRef})
+			i += len(errRef)
+		case c == '<' || c == '>':
+			if i+1 < len(src) && (src[i+1] == '=' || c == '<' && src[i+1] == '>') {
+				tokens = append(tokens, token{tokOp, src[i : i+2]})
+				i += 2
+			} else {
+				tokens = append(tokens, token{tokOp, string(c)})
+				i++
+			}
+		case strings.IndexByte("+-*/^&=%", c) >= 0:
+			tokens = append(tokens, token{tokOp, string(c)})
+			i++
+		default:
+			return nil, false
+		}
+	}
+	return append(tokens, token{tokEOF, ""}), true
+}
+
+// A node of a parsed formula.
+type node interface {
+	eval(e *evaluator) Value
+}
+
+type numberNode float64
+type stringNode string
+type boolNode bool
+type refNode Address
+type rangeNode Range
+type errorNode string
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+// A recursive descent formula parser.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse the formula source, without the leading "=", into a node.
+func parseFormula(src string) (node, bool) {
+	tokens, ok := tokenize(src)
+	if !ok {
+		return nil, false
+	}
+	p := parser{tokens: tokens}
+	n, ok := p.comparison()
+	if !ok || p.peek().typ != tokEOF {
+		return nil, false
+	}
+	return n, true
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.typ != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// comparison := concat (("=" | "<>" | "<" | ">" | "<=" | ">=") concat)*
+func (p *parser) comparison() (node, bool) {
+	return p.binary(p.concat, "=", "<>", "<", ">", "<=", ">=")
+}
+
+// concat := additive ("&" additive)*
+func (p *parser) concat() (node, bool) {
+	return p.binary(p.additive, "&")
+}
+
+// additive := term (("+" | "-") term)*
+func (p *parser) additive() (node, bool) {
+	return p.binary(p.term, "+", "-")
+}
+
+// term := power (("*" | "/") power)*
+func (p *parser) term() (node, bool) {
+	return p.binary(p.power, "*", "/")
+}
+
+// power := unary ("^" unary)*
+func (p *parser) power() (node, bool) {
+	return p.binary(p.unary, "^")
+}
+
+func (p *parser) binary(operand func() (node, bool), ops ...string) (node, bool) {
+	left, ok := operand()
+	if !ok {
+		return nil, false
+	}
+	for p.isOp(ops...) {
+		op := p.next().text
+		right, ok := operand()
+		if !ok {
+			return nil, false
+		}
+		left = binaryNode{op, left, right}
+	}
+	return left, true
+}
+
+// unary := ("+" | "-") unary | primary "%"?
+func (p *parser) unary() (node, bool) {
+	if p.isOp("+", "-") {
+		op := p.next().text
+		n, ok := p.unary()
+		if !ok {
+			return nil, false
+		}
+		return unaryNode{op, n}, true
+	}
+	n, ok := p.primary()
+	if ok && p.isOp("%") {
+		p.next()
+		n = unaryNode{"%", n}
+	}
+	return n, ok
+}
+
+func (p *parser) primary() (node, bool) {
+	t := p.next()
+	switch t.typ {
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, false
+		}
+		return numberNode(n), true
+	case tokString:
+		return stringNode(t.text), true
+	case tokError:
+		return errorNode(t.text), true
+	case tokLParen:
+		n, ok := p.comparison()
+		if !ok || p.next().typ != tokRParen {
+			return nil, false
+		}
+		return n, true
+	case tokIdent:
+		if p.peek().typ == tokLParen {
+			return p.call(strings.ToUpper(t.text))
+		}
+		if a, ok := ParseAddress(t.text); ok {
+			if p.peek().typ == tokColon {
+				p.next()
+				b, ok := ParseAddress(p.next().text)
+				if !ok {
+					return nil, false
+				}
+				return rangeNode(NewRange(a, b)), true
+			}
+			return refNode(a), true
+		}
+		switch strings.ToUpper(t.text) {
+		case "TRUE":
+			return boolNode(true), true
+		case "FALSE":
+			return boolNode(false), true
+		}
+		return callNode{name: strings.ToUpper(t.text)}, true
+	}
+	return nil, false
+}
+
+func (p *parser) call(name string) (node, bool) {
+	p.next()
+	c := callNode{name: name, args: []node{}}
+	if p.peek().typ == tokRParen {
+		p.next()
+		return c, true
+	}
+	for {
+		arg, ok := p.comparison()
+		if !ok {
+			return nil, false
+		}
+		c.args = append(c.args, arg)
+		switch p.next().typ {
+		case tokComma:
+			continue
+		case tokRParen:
+			return c, true
+		}
+		return nil, false
+	}
+}
+
+// Evaluates formula nodes. The lookup function returns the value of
+// the cell at an address.
+type evaluator struct {
+	lookup func(a Address) Value
+}
+
+func (n numberNode) eval(e *evaluator) Value {
+	return NumberVal(float64(n))
+}
+
+func (n stringNode) eval(e *evaluator) Value {
+	return TextVal(string(n))
+}
+
+func (n boolNode) eval(e *evaluator) Value {
+	return BoolVal(bool(n))
+}
+
+func (n errorNode) eval(e *evaluator) Value {
+	return ErrorVal(string(n))
+}
+
+func (n refNode) eval(e *evaluator) Value {
+	return e.lookup(Address(n))
+}
+
+// A range used as a single value is an error.
+func (n rangeNode) eval(e *evaluator) Value {
+	return ErrorVal(errValue)
+}
+
+func (n unaryNode) eval(e *evaluator) Value {
+	v, err := n.operand.eval(e).toNumber()
+	if err != nil {
+		return *err
+	}
+	switch n.op {
+	case "-":
+		return NumberVal(-v)
+	case "%":
+		return NumberVal(v / 100)
+	}
+	return NumberVal(v)
+}
+
+func (n binaryNode) eval(e *evaluator) Value {
+	l := n.left.eval(e)
+	if l.kind == ErrorKind {
+		return l
+	}
+	r := n.right.eval(e)
+	if r.kind == ErrorKind {
+		return r
+	}
+	switch n.op {
+	case "&":
+		return TextVal(l.String() + r.String())
+	case "=", "<>", "<", ">", "<=", ">=":
+		return compare(n.op, l, r)
+	}
+	a, err := l.toNumber()
+	if err != nil {
+		return *err
+	}
+	b, err := r.toNumber()
+	if err != nil {
+		return *err
+	}
+	switch n.op {
+	case "+":
+		return NumberVal(a + b)
+	case "-":
+		return NumberVal(a - b)
+	case "*":
+		return NumberVal(a * b)
+	case "/":
+		if b == 0 {
+			return ErrorVal(errDiv0)
+		}
+		return NumberVal(a / b)
+	case "^":
+		return NumberVal(math.Pow(a, b))
+	}
+	return ErrorVal(errValue)
+}
+
+// Compare two values with a comparison operator.
+func compare(op string, l, r Value) Value {
+	// An empty cell equals zero, empty text or FALSE.
+	if l.kind == EmptyKind {
+		l = emptyAs(r)
+	}
+	if r.kind == EmptyKind {
+		r = emptyAs(l)
+	}
+	c := l.Compare(r)
+	switch op {
+	case "=":
+		return BoolVal(c == 0)
+	case "<>":
+		return BoolVal(c != 0)
+	case "<":
+		return BoolVal(c < 0)
+	case ">":
+		return BoolVal(c > 0)
+	case "<=":
+		return BoolVal(c <= 0)
+	}
+	return BoolVal(c >= 0)
+}
+
+func emptyAs(v Value) Value {
+	switch v.kind {
+	case TextKind:
+		return Value{kind: TextKind}
+	case BoolKind:
+		return BoolVal(false)
+	}
+	return NumberVal(0)
+}
+
+// A function argument value. Values that came from a cell reference
+// or range are flagged so aggregate functions can skip text in them.
+type arg struct {
+	Value
+	ref bool
+}
+
+// Evaluate function arguments, expanding ranges into their cells.
+func (e *evaluator) args(nodes []node) []arg {
+	args := []arg{}
+	for _, n := range nodes {
+		switch r := n.(type) {
+		case rangeNode:
+			for row := r.From.Row; row <= r.To.Row; row++ {
+				for col := r.From.Col; col <= r.To.Col; col++ {
+					args = append(args, arg{e.lookup(Address{row, col}), true})
+				}
+			}
+		case refNode:
+			args = append(args, arg{r.eval(e), true})
+		default:
+			args = append(args, arg{n.eval(e), false})
+		}
+	}
+	return args
+}
+
+// Collect the numeric arguments for an aggregate function.
+func (e *evaluator) numbers(nodes []node) ([]float64, *Value) {
+	nums := []float64{}
+	for _, a := range e.args(nodes) {
+		if a.kind == ErrorKind {
+			return nil, &a.Value
+		}
+		if a.ref && a.kind != NumberKind && a.kind != DateKind {
+			continue
+		}
+		n, err := a.toNumber()
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+// The built-in formula functions.
+var functions = map[string]func(e *evaluator, args []node) Value{
+	"SUM": func(e *evaluator, args []node) Value {
+		nums, err := e.numbers(args)
+		if err != nil {
+			return *err
+		}
+		sum := 0.0
+		for _, n := range nums {
+			sum += n
+		}
+		return NumberVal(sum)
+	},
+	"AVERAGE": func(e *evaluator, args []node) Value {
+		nums, err := e.numbers(args)
+		if err != nil {
+			return *err
+		}
+		if len(nums) == 0 {
+			return ErrorVal(errDiv0)
+		}
+		sum := 0.0
+		for _, n := range nums {
+			sum += n
+		}
+		return NumberVal(sum / float64(len(nums)))
+	},
+	"MIN": func(e *evaluator, args []node) Value {
+		nums, err := e.numbers(args)
+		if err != nil {
+			return *err
+		}
+		if len(nums) == 0 {
+			return NumberVal(0)
+		}
+		min := nums[0]
+		for _, n := range nums[1:] {
+			min = math.Min(min, n)
+		}
+		return NumberVal(min)
+	},
+	"MAX": func(e *evaluator, args []node) Value {
+		nums, err := e.numbers(args)
+		if err != nil {
+			return *err
+		}
+		if len(nums) == 0 {
+			return NumberVal(0)
+		}
+		max := nums[0]
+		for _, n := range nums[1:] {
+			max = math.Max(max, n)
+		}
+		return NumberVal(max)
+	},
+	"IF": func(e *evaluator, args []node) Value {
+		if len(args) < 2 || len(args) > 3 {
+			return ErrorVal(errValue)
+		}
+		cond := args[0].eval(e)
+		if cond.kind == ErrorKind {
+			return cond
+		}
+		b, err := cond.toBool()
+		if err != nil {
+			return *err
+		}
+		if b {
+			return args[1].eval(e)
+		}
+		if len(args) == 3 {
+			return args[2].eval(e)
+		}
+		return BoolVal(false)
+	},
+	"CONCAT": func(e *evaluator, args []node) Value {
+		s := ""
+		for _, a := range e.args(args) {
+			if a.kind == ErrorKind {
+				return a.Value
+			}
+			s += a.String()
+		}
+		return TextVal(s)
+	},
+}
+
+func (n callNode) eval(e *evaluator) Value {
+	f, ok := functions[n.name]
+	if !ok || n.args == nil {
+		return ErrorVal(errName)
+	}
+	return f(e, n.args)
+}
+
+// Evaluate a parsed formula. The lookup function returns the value
+// of the cell at an address.
+func evalFormula(n node, lookup func(a Address) Value) Value {
+	e := evaluator{lookup}
+	v := n.eval(&e)
+	// An empty result of a reference displays as zero.
+	if v.kind == EmptyKind {
+		return NumberVal(0)
+	}
+	return v
+}
+
+// The ranges referenced by a formula. A single cell reference is a
+// one cell range.
+func refs(n node) []Range {
+	switch n := n.(type) {
+	case refNode:
+		return []Range{{Address(n), Address(n)}}
+	case rangeNode:
+		return []Range{Range(n)}
+	case unaryNode:
+		return refs(n.operand)
+	case binaryNode:
+		return append(refs(n.left), refs(n.right)...)
+	case callNode:
+		r := []Range{}
+		for _, a := range n.args {
+			r = append(r, refs(a)...)
+		}
+		return r
+	}
+	return nil
+}
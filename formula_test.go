@@ -0,0 +1,129 @@
+package grid
+
+import (
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		s  string
+		a  Address
+		ok bool
+	}{
+		{"A1", Address{0, 0}, true},
+		{"$b$12", Address{11, 1}, true},
+		{"AA100", Address{99, 26}, true},
+		{"A1073741824", Address{1073741823, 0}, true},
+		{"A1073741825", Address{}, false},
+		{"A922337203685477580", Address{}, false},
+		{"A0", Address{}, false},
+		{"A+1", Address{}, false},
+		{"1A", Address{}, false},
+		{"A", Address{}, false},
+		{"ZZZZZZ1", Address{}, false},
+	}
+	for _, tt := range tests {
+		a, ok := ParseAddress(tt.s)
+		if ok != tt.ok || ok && a != tt.a {
+			t.Errorf("ParseAddress(%q) = %v, %v, want %v, %v", tt.s, a, ok, tt.a, tt.ok)
+		}
+	}
+}
+
+func TestEvalFormula(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	for ref, v := range map[string]string{
+		"A1": "1", "A2": "2", "A3": "3", "B1": "text", "B2": "TRUE", "B3": "#N/A",
+	} {
+		a := a1(ref)
+		sh.addData(a.Row, a.Col, v)
+	}
+	tests := []struct {
+		formula string
+		want    string
+	}{
+		{"1+2*3", "7"},
+		{"(1+2)*3", "9"},
+		{"2^3^2", "64"},
+		{"-2^2", "4"},
+		{"10-4-3", "3"},
+		{"8/4/2", "1"},
+		{"1+2&\"x\"", "3x"},
+		{"1+2=3", "TRUE"},
+		{"\"a\"<\"B\"", "TRUE"},
+		{"A1+A2*A3", "7"},
+		{"$A$3-A1", "2"},
+		{"C1+1", "1"},
+		{"SUM(A1:A3)", "6"},
+		{"SUM(A1:B2)", "3"},
+		{"sum(A1:A3, 4)", "10"},
+		{"AVERAGE(A1:A3)", "2"},
+		{"MIN(A3:A1)", "1"},
+		{"MAX(A1:A3, 10)", "10"},
+		{"MIN(C1:C3)", "0"},
+		{"IF(A1>1, \"big\", \"small\")", "small"},
+		{"IF(B2, A1)", "1"},
+		{"IF(A1=2, 1)", "FALSE"},
+		{"CONCAT(B1, \"-\", A1:A3)", "text-123"},
+		{"1/0", errDiv0},
+		{"AVERAGE(C1:C3)", errDiv0},
+		{"B1+1", errValue},
+		{"SUM(A1, \"x\")", errValue},
+		{"B3+1", errNA},
+		{"SUM(A1:B3)+B3", errNA},
+		{"NOPE(1)", errName},
+		{"IF(1)", errValue},
+		{"1+", errParse},
+		{"(1", errParse},
+		{"SUM(A1:A3", errParse},
+	}
+	for _, tt := range tests {
+		c := sh.addData(5, 5, "="+tt.formula)
+		if got := c.val.String(); got != tt.want {
+			t.Errorf("=%s = %q, want %q", tt.formula, got, tt.want)
+		}
+	}
+}
+
+func TestRecalc(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	value := func(ref string) string {
+		return sh.cellAt(a1(ref)).val.String()
+	}
+	// The formulas are entered before the cells they reference.
+	sh.addData(0, 2, "=B1*2")
+	sh.addData(0, 1, "=SUM(A1:A3)")
+	sh.addData(1, 2, "=C1+B1")
+	sh.addData(0, 0, "1")
+	sh.addData(1, 0, "2")
+	if got := value("C2"); got != "9" {
+		t.Errorf("C2 = %s, want 9", got)
+	}
+	sh.addData(2, 0, "3")
+	for ref, want := range map[string]string{"B1": "6", "C1": "12", "C2": "18"} {
+		if got := value(ref); got != want {
+			t.Errorf("after changing A3 %s = %s, want %s", ref, got, want)
+		}
+	}
+
+	// A cycle shows #CIRC! in its cells and the cells depending on
+	// them, and recovers once it is broken.
+	sh.addData(2, 0, "=C2")
+	for _, ref := range []string{"A3", "B1", "C1", "C2"} {
+		if got := value(ref); got != errCirc {
+			t.Errorf("with a cycle %s = %s, want %s", ref, got, errCirc)
+		}
+	}
+	if got := value("A1"); got != "1" {
+		t.Errorf("with a cycle A1 = %s, want 1", got)
+	}
+	sh.addData(2, 0, "=A1")
+	for ref, want := range map[string]string{"A3": "1", "B1": "4", "C1": "8", "C2": "12"} {
+		if got := value(ref); got != want {
+			t.Errorf("after breaking the cycle %s = %s, want %s", ref, got, want)
+		}
+	}
+	if got := sh.addData(4, 4, "=E5+1").val.String(); got != errCirc {
+		t.Errorf("a self reference = %s, want %s", got, errCirc)
+	}
+}
@@ -0,0 +1,80 @@
+package grid
+
+// A pane of the view-port. The cells below and right of the frozen
+// rows and columns scroll in the main pane. The frozen rows scroll only
+// horizontally, the frozen columns only vertically and the corner they
+// share does not scroll.
+type pane struct {
+	vx, vy int // view-port position
+	w, h   int // size
+	gx, gy int // grid coordinates shown at the view-port position
+}
+
+// Freeze the first rows and cols of the grid so they stay visible
+// while the rest of the grid scrolls. Zero unfreezes.
+func (sh *Sheet) FreezePanes(rows, cols int) {
+	if rows < 0 {
+		rows = 0
+	}
+	if cols < 0 {
+		cols = 0
+	}
+	sh.frozenRows = rows
+	sh.frozenCols = cols
+}
+
+// The width of the frozen columns and the height of the frozen rows.
+func (sh *Sheet) frozenSize() (int, int) {
+	return sh.cols.offset(sh.frozenCols), sh.rows.offset(sh.frozenRows)
+}
+
+// The panes of the view-port. The main pane is first, followed by the
+// frozen rows, the frozen columns and the frozen corner. The index of
+// a cell's pane is given by paneIndex.
+func (sh *Sheet) panes() []pane {
+	fw, fh := sh.frozenSize()
+	hw, hh := sh.headerWidth, sh.headerHeight
+	w := sh.width - hw - fw
+	h := sh.height - hh - fh
+	return []pane{
+		{hw + fw, hh + fh, w, h, fw + sh.x, fh + sh.y},
+		{hw + fw, hh, w, fh, fw + sh.x, 0},
+		{hw, hh + fh, fw, h, 0, fh + sh.y},
+		{hw, hh, fw, fh, 0, 0},
+	}
+}
+
+// The index of the pane a cell is drawn in.
+func (sh *Sheet) paneIndex(row, col int) int {
+	i := 0
+	if row < sh.frozenRows {
+		i++
+	}
+	if col < sh.frozenCols {
+		i += 2
+	}
+	return i
+}
+
+// Draw the lines dividing the frozen panes from the scrolling ones.
+func (sh *Sheet) drawFreezeLines(r Renderer) {
+	if sh.frozenRows == 0 && sh.frozenCols == 0 {
+		return
+	}
+	fw, fh := sh.frozenSize()
+	hw, hh := float64(sh.headerWidth), float64(sh.headerHeight)
+	r.Save()
+	r.SetStrokeStyle("darkgray")
+	r.SetLineWidth(2)
+	r.BeginPath()
+	if sh.frozenCols > 0 {
+		r.MoveTo(hw+float64(fw), hh)
+		r.LineTo(hw+float64(fw), float64(sh.height))
+	}
+	if sh.frozenRows > 0 {
+		r.MoveTo(hw, hh+float64(fh))
+		r.LineTo(float64(sh.width), hh+float64(fh))
+	}
+	r.Stroke()
+	r.Restore()
+}
@@ -0,0 +1,135 @@
+package grid
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// The grapheme cluster properties of a rune used to find the boundaries
+// of user-perceived characters. This covers the rules of Unicode UAX #29
+// that matter for cell text: CR LF, combining marks, emoji modifier and
+// ZWJ sequences, regional indicator flags and Hangul syllables.
+type graphemeProp int
+
+const (
+	gpOther graphemeProp = iota
+	gpCR
+	gpLF
+	gpControl
+	gpExtend
+	gpZWJ
+	gpRegional
+	gpSpacingMark
+	gpL
+	gpV
+	gpT
+	gpLV
+	gpLVT
+	gpPictographic
+)
+
+func graphemePropOf(r rune) graphemeProp {
+	switch {
+	case r == '\r':
+		return gpCR
+	case r == '\n':
+		return gpLF
+	case r == 0x200D:
+		return gpZWJ
+	case r >= 0x1F1E6 && r <= 0x1F1FF:
+		return gpRegional
+	case r >= 0x1F3FB && r <= 0x1F3FF, // emoji modifiers
+		r >= 0xFE00 && r <= 0xFE0F,   // variation selectors
+		r >= 0xE0020 && r <= 0xE007F, // tags
+		r >= 0xE0100 && r <= 0xE01EF,
+		r == 0x200C,
+		unicode.In(r, unicode.Mn, unicode.Me):
+		return gpExtend
+	case unicode.Is(unicode.Mc, r):
+		return gpSpacingMark
+	case unicode.IsControl(r), r == 0x2028, r == 0x2029:
+		return gpControl
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gpL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gpV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gpT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return gpLV
+		}
+		return gpLVT
+	case r >= 0x1F000 && r <= 0x1FAFF, r >= 0x2600 && r <= 0x27BF,
+		r == 0x00A9, r == 0x00AE, r == 0x203C, r == 0x2049, r == 0x2122,
+		r >= 0x2190 && r <= 0x21FF, r >= 0x2300 && r <= 0x23FF,
+		r >= 0x2B00 && r <= 0x2BFF, r == 0x3030, r == 0x303D:
+		return gpPictographic
+	}
+	return gpOther
+}
+
+// The byte offset of the end of the grapheme cluster starting at i.
+func nextGrapheme(s string, i int) int {
+	if i >= len(s) {
+		return len(s)
+	}
+	r, n := utf8.DecodeRuneInString(s[i:])
+	prev := graphemePropOf(r)
+	// Track emoji ZWJ sequences and regional indicator pairs.
+	pictographic := prev == gpPictographic
+	regional := 0
+	if prev == gpRegional {
+		regional = 1
+	}
+	for i += n; i < len(s); i += n {
+		r, n = utf8.DecodeRuneInString(s[i:])
+		p := graphemePropOf(r)
+		if !graphemeJoins(prev, p, pictographic, regional) {
+			break
+		}
+		if p == gpRegional {
+			regional++
+		}
+		if p == gpPictographic {
+			pictographic = true
+		} else if p != gpExtend && p != gpZWJ {
+			pictographic = false
+		}
+		prev = p
+	}
+	return i
+}
+
+// Check if there is no grapheme cluster boundary between runes with the
+// properties prev and p.
+func graphemeJoins(prev, p graphemeProp, pictographic bool, regional int) bool {
+	switch {
+	case prev == gpCR && p == gpLF:
+		return true
+	case prev == gpCR || prev == gpLF || prev == gpControl,
+		p == gpCR || p == gpLF || p == gpControl:
+		return false
+	case prev == gpL && (p == gpL || p == gpV || p == gpLV || p == gpLVT),
+		(prev == gpLV || prev == gpV) && (p == gpV || p == gpT),
+		(prev == gpLVT || prev == gpT) && p == gpT:
+		return true
+	case p == gpExtend || p == gpZWJ || p == gpSpacingMark:
+		return true
+	case prev == gpZWJ && p == gpPictographic && pictographic:
+		return true
+	case prev == gpRegional && p == gpRegional:
+		return regional%2 == 1
+	}
+	return false
+}
+
+// The byte offset of the start of the grapheme cluster ending at i.
+func prevGrapheme(s string, i int) int {
+	start := 0
+	for j := 0; j < i; {
+		start = j
+		j = nextGrapheme(s, j)
+	}
+	return start
+}
@@ -0,0 +1,107 @@
+package grid
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Split text into grapheme clusters forwards and backwards.
+func graphemes(s string) ([]string, []string) {
+	var forward, backward []string
+	for i := 0; i < len(s); {
+		j := nextGrapheme(s, i)
+		forward = append(forward, s[i:j])
+		i = j
+	}
+	for i := len(s); i > 0; {
+		j := prevGrapheme(s, i)
+		backward = append([]string{s[j:i]}, backward...)
+		i = j
+	}
+	return forward, backward
+}
+
+func TestGraphemes(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{"ascii", "abc", []string{"a", "b", "c"}},
+		{"crlf", "a\r\nb", []string{"a", "\r\n", "b"}},
+		{"combining marks", "éạ̈", []string{"é", "ạ̈"}},
+		{"zwj sequence", "👩‍👩‍👧x", []string{"👩‍👩‍👧", "x"}},
+		{"skin tone", "👍🏽👍", []string{"👍🏽", "👍"}},
+		{"variation selector", "❤️!", []string{"❤️", "!"}},
+		{"flags", "🇯🇵🇫🇷🇩", []string{"🇯🇵", "🇫🇷", "🇩"}},
+		{"cjk", "漢字かな", []string{"漢", "字", "か", "な"}},
+		{"hangul", "각한", []string{"각", "한"}},
+	}
+	for _, tt := range tests {
+		forward, backward := graphemes(tt.s)
+		if !reflect.DeepEqual(forward, tt.want) {
+			t.Errorf("%s: nextGrapheme splits %q into %q, want %q", tt.name, tt.s, forward, tt.want)
+		}
+		if !reflect.DeepEqual(backward, tt.want) {
+			t.Errorf("%s: prevGrapheme splits %q into %q, want %q", tt.name, tt.s, backward, tt.want)
+		}
+	}
+}
+
+// Backspace and Delete remove a whole grapheme cluster.
+func TestEditorGraphemes(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"aé", "a"},
+		{"a👩‍👩‍👧", "a"},
+		{"🇯🇵🇫🇷", "🇯🇵"},
+		{"漢字", "漢"},
+	}
+	for _, tt := range tests {
+		e := editor{}
+		e.start(tt.text, true)
+		e.deleteBack()
+		if e.text != tt.want {
+			t.Errorf("backspace on %q = %q, want %q", tt.text, e.text, tt.want)
+		}
+		e.start(tt.text, true)
+		e.moveTo(e.prev(len(tt.text)), false)
+		e.deleteForward()
+		if e.text != tt.want {
+			t.Errorf("delete before the last character of %q = %q, want %q", tt.text, e.text, tt.want)
+		}
+	}
+}
+
+// A Renderer measuring each grapheme cluster as 10 pixels wide.
+type graphemeRenderer struct {
+	Renderer
+}
+
+func (graphemeRenderer) MeasureText(s string) float64 {
+	forward, _ := graphemes(s)
+	return float64(10 * len(forward))
+}
+
+func TestFitText(t *testing.T) {
+	tests := []struct {
+		s    string
+		w    int
+		want string
+	}{
+		{"abc", 30, "abc"},
+		{"abcd", 30, "ab…"},
+		{"éééé", 30, "éé…"},
+		{"👩‍👩‍👧👍🏽🇯🇵", 25, "👩‍👩‍👧…"},
+		{"🇯🇵🇫🇷🇩🇪", 20, "🇯🇵…"},
+		{"漢字かな", 35, "漢字…"},
+		{"abc", 10, "…"},
+		{"abc", 5, ""},
+	}
+	for _, tt := range tests {
+		if got := fitText(graphemeRenderer{}, tt.s, tt.w); got != tt.want {
+			t.Errorf("fitText(%q, %d) = %q, want %q", tt.s, tt.w, got, tt.want)
+		}
+	}
+}
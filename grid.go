@@ -1,3 +1,6 @@
+//go:build js && wasm
+// +build js,wasm
+
 // Package grid provides the implementation of a wasm based grid control.
 // The control can be created and modified from JavaScript code by using
 // the public API methods defined in the js.go file. The grid package will
@@ -10,7 +13,10 @@
 package grid
 
 import (
+	"io"
+	"strings"
 	"syscall/js"
+	"unicode/utf8"
 )
 
 // grid scroll directions.
@@ -24,27 +30,30 @@ const (
 	up
 )
 
-// The main grid type.
+// The main grid type. The grid draws its Sheet onto a canvas and
+// handles the browser events.
 type grid struct {
+	*Sheet
 	class          string
-	x, y, sx, sy   int
-	width, height  int
+	sx, sy         int
 	vcnv, cnv, ctx js.Value
 	main           js.Value
-	selectedCells  map[Address]*cell
-	data           map[Address]*cell
 	cellWidth      int
 	cellHeight     int
 	direction      direction // scroll direction
-	interval       js.Value // scroll timer interval
-	speed          int // scroll speed.
-	editCell       *cell
+	interval       js.Value  // scroll timer interval
+	speed          int       // scroll speed.
 	scrolling      bool
 	active         bool
 	mouseDown      bool
-	scrollAmt	int
-	lastScroll	int
-	container Container
+	scrollAmt      int
+	lastScroll     int
+	container      Container
+	bgX, bgY       int      // grid coordinates of the background canvas origin
+	resizing       *resize  // column or row being resized with the mouse
+	focused        bool     // the last mouse down was on the grid
+	input          js.Value // hidden input receiving IME text
+	renderer       Renderer // draws onto ctx
 }
 
 // The public interface for a grid.
@@ -52,6 +61,7 @@ type Grid interface {
 	Draw()
 	AddEventHandler(event string, handler func(this js.Value, args []js.Value) interface{})
 	GetCtx() *js.Value
+	GetRenderer() Renderer
 	GetCellWidth() int
 	GetCellHeight() int
 	GetX() int
@@ -60,18 +70,58 @@ type Grid interface {
 	AddContainer(container Container)
 	GetElement() *js.Value
 	AddData(row, col int, value string)
+	AddValue(row, col int, v Value)
 	GetContainer() Container
+	GetSheet() *Sheet
 	SelectCells([]Address)
+	GetSelection() []Range
 	ClearSelection()
 	AddColumn(col, count int)
 	AddRow(row, count int)
+	DeleteColumn(col, count int)
+	DeleteRow(row, count int)
 	GetCellContent(row, col int) CellContent
+	SetCellFormat(row, col int, format string)
+	SetColumnFormat(col int, format string)
+	SetRangeFormat(r Range, format string)
+	GetCellFormat(row, col int) string
+	SetColumnWidth(col, width int)
+	SetRowHeight(row, height int)
+	GetColumnWidth(col int) int
+	GetRowHeight(row int) int
+	SetColumnHeader(col int, label string)
+	SetRowHeader(row int, label string)
+	FreezePanes(rows, cols int)
+	SetCellStyle(row, col int, s CellStyle)
+	SetRowStyle(row int, s CellStyle)
+	SetColumnStyle(col int, s CellStyle)
+	SetRangeStyle(r Range, s CellStyle)
+	GetCellStyle(row, col int) CellStyle
+	OutlineRange(r Range, b Border)
+	SetRangeBorders(r Range, b Border)
+	ClearRangeBorders(r Range)
+	SetCellAlignment(row, col int, align Alignment)
+	SetColumnAlignment(col int, align Alignment)
+	SetRangeAlignment(r Range, align Alignment)
+	GetCellAlignment(row, col int) Alignment
+	MergeCells(r Range)
+	UnmergeCells(r Range)
+	GetMerges() []Range
+	Undo()
+	Redo()
+	CanUndo() bool
+	CanRedo() bool
+	BeginUpdate()
+	EndUpdate()
+	ImportCSV(r io.Reader, anchor Address, opts CSVOptions) (Range, error)
+	ExportCSV(w io.Writer, r Range, opts CSVOptions) error
 }
 
 // The Container interface provides the methods for the grid.container.
 // The grid.container allows for adding event handlers and cell styles
 // to the grid while letting the grid handle the standard events and
-// cell styles.
+// cell styles. A Container can also implement RecalcListener,
+// ResizeListener and AlignmentStyler.
 type Container interface {
 	AddCell(cell CellContent)
 	AddCellsDone()
@@ -80,89 +130,167 @@ type Container interface {
 	GetGrid() Grid
 }
 
-func (g *grid) GetCellContent(row, col int) CellContent {
-	a := Address{row, col}
-	if _, ok := g.data[a]; !ok {
-		return g.addData(row, col, "")
-	}
-	return g.data[a]
+func (g *grid) Draw() {
+	g.draw()
+}
+
+// The grid methods that change the Sheet draw the change.
+
+func (g *grid) SelectCells(addresses []Address) {
+	g.Sheet.SelectCells(addresses)
+	g.draw()
 }
 
 func (g *grid) AddColumn(col, count int) {
-	columns := []*cell{}
-	selectedColumns := []*cell{}
-	for k, v := range g.data {
-		if v.col >= col {
-			v.col+=count
-			v.x = v.col * g.cellWidth
-			if _, ok := g.selectedCells[k]; ok {
-				delete(g.selectedCells, k)
-				selectedColumns = append(selectedColumns, v)
-			}
-			delete(g.data, k)
-			columns = append(columns, v)
-		}
-	}
-	for _, c := range columns {
-		g.data[Address{c.row, c.col}] = c
-	}
-	for _, c := range selectedColumns {
-		g.selectedCells[Address{c.row, c.col}] = c
-	}
-	columns = []*cell{}
-	for k, v := range g.selectedCells {
-		if _, ok := g.data[k]; ok {
-			continue
-		}
-		if v.col >= col {
-			v.col+=count
-			v.x = v.col * g.cellWidth
-			delete(g.selectedCells, k)
-			columns = append(columns, v)
-		}
-	}
-	for _, c := range columns {
-		g.selectedCells[Address{c.row, c.col}] = c
-	}
+	g.Sheet.AddColumn(col, count)
+	g.drawBackGround()
+	g.draw()
 }
 
 func (g *grid) AddRow(row, count int) {
-	for k, v := range g.data {
-		if v.row >= row - 1 {
-			v.row+=count
-			v.y = v.row * g.cellHeight
-			delete(g.data, k)
-			g.data[Address{v.row, v.col}] = v
-		}
-	}
-	for k, v := range g.selectedCells {
-		if v.row >= row - 1 {
-			v.row+=count
-			v.y = v.row * g.cellHeight
-			delete(g.data, k)
-			g.data[Address{v.row, v.col}] = v
-		}
-	}
+	g.Sheet.AddRow(row, count)
+	g.drawBackGround()
+	g.draw()
 }
 
-func (g *grid) Draw() {
+func (g *grid) DeleteColumn(col, count int) {
+	g.Sheet.DeleteColumn(col, count)
+	g.drawBackGround()
 	g.draw()
 }
 
-func (g *grid) AddData(row, col int, value string) {
-	g.addData(row, col, value)
+func (g *grid) DeleteRow(row, count int) {
+	g.Sheet.DeleteRow(row, count)
+	g.drawBackGround()
+	g.draw()
 }
 
-func (g *grid) SelectCells(addresses []Address) {
-	for _, a := range addresses {
-		x, y := g.addressToCoords(a.Row, a.Col)
-		g.selectCellAddress(a, x, y)
-	}
+func (g *grid) SetCellFormat(row, col int, format string) {
+	g.Sheet.SetCellFormat(row, col, format)
 	g.draw()
 }
 
-func (g *grid) ClearSelection() {
-	g.selectedCells = map[Address]*cell{}
+func (g *grid) SetColumnFormat(col int, format string) {
+	g.Sheet.SetColumnFormat(col, format)
+	g.draw()
+}
+
+func (g *grid) SetRangeFormat(r Range, format string) {
+	g.Sheet.SetRangeFormat(r, format)
+	g.draw()
+}
+
+func (g *grid) SetColumnWidth(col, width int) {
+	g.Sheet.SetColumnWidth(col, width)
+	g.drawBackGround()
+	g.draw()
+}
+
+func (g *grid) SetRowHeight(row, height int) {
+	g.Sheet.SetRowHeight(row, height)
+	g.drawBackGround()
+	g.draw()
+}
+
+func (g *grid) SetColumnHeader(col int, label string) {
+	g.Sheet.SetColumnHeader(col, label)
+	g.draw()
+}
+
+func (g *grid) SetRowHeader(row int, label string) {
+	g.Sheet.SetRowHeader(row, label)
+	g.draw()
+}
+
+func (g *grid) FreezePanes(rows, cols int) {
+	g.Sheet.FreezePanes(rows, cols)
+	g.draw()
+}
+
+func (g *grid) SetCellStyle(row, col int, s CellStyle) {
+	g.Sheet.SetCellStyle(row, col, s)
+	g.draw()
+}
+
+func (g *grid) SetRowStyle(row int, s CellStyle) {
+	g.Sheet.SetRowStyle(row, s)
+	g.draw()
+}
+
+func (g *grid) SetColumnStyle(col int, s CellStyle) {
+	g.Sheet.SetColumnStyle(col, s)
+	g.draw()
+}
+
+func (g *grid) SetRangeStyle(r Range, s CellStyle) {
+	g.Sheet.SetRangeStyle(r, s)
+	g.draw()
+}
+
+func (g *grid) OutlineRange(r Range, b Border) {
+	g.Sheet.OutlineRange(r, b)
+	g.draw()
+}
+
+func (g *grid) SetRangeBorders(r Range, b Border) {
+	g.Sheet.SetRangeBorders(r, b)
+	g.draw()
+}
+
+func (g *grid) ClearRangeBorders(r Range) {
+	g.Sheet.ClearRangeBorders(r)
+	g.draw()
+}
+
+func (g *grid) SetCellAlignment(row, col int, align Alignment) {
+	g.Sheet.SetCellAlignment(row, col, align)
+	g.draw()
+}
+
+func (g *grid) SetColumnAlignment(col int, align Alignment) {
+	g.Sheet.SetColumnAlignment(col, align)
+	g.draw()
+}
+
+func (g *grid) SetRangeAlignment(r Range, align Alignment) {
+	g.Sheet.SetRangeAlignment(r, align)
+	g.draw()
+}
+
+func (g *grid) MergeCells(r Range) {
+	g.Sheet.MergeCells(r)
+	g.draw()
+}
+
+func (g *grid) UnmergeCells(r Range) {
+	g.Sheet.UnmergeCells(r)
+	g.draw()
+}
+
+func (g *grid) Undo() {
+	g.Sheet.Undo()
+	g.draw()
+}
+
+func (g *grid) Redo() {
+	g.Sheet.Redo()
+	g.draw()
+}
+
+func (g *grid) ImportCSV(r io.Reader, anchor Address, opts CSVOptions) (Range, error) {
+	rg, err := g.Sheet.ImportCSV(r, anchor, opts)
+	g.draw()
+	return rg, err
+}
+
+// The alignment of a cell from its style. The container can override
+// it.
+func (g *grid) GetCellAlignment(row, col int) Alignment {
+	return g.alignment(row, col)
+}
+
+func (g *grid) GetSheet() *Sheet {
+	return g.Sheet
 }
 
 func (g *grid) AddEventHandler(event string, handler func(this js.Value, args []js.Value) interface{}) {
@@ -173,6 +301,10 @@ func (g *grid) GetCtx() *js.Value {
 	return &g.ctx
 }
 
+func (g *grid) GetRenderer() Renderer {
+	return g.renderer
+}
+
 func (g *grid) GetCellWidth() int {
 	return g.cellWidth
 }
@@ -181,23 +313,10 @@ func (g *grid) GetCellHeight() int {
 	return g.cellHeight
 }
 
-func (g *grid) GetX() int {
-	return g.x
-}
-
-func (g *grid) GetY() int {
-	return g.y
-}
-
-func (g *grid) GetEditCellAddress() *Address {
-	if g.editCell == nil {
-		return nil
-	}
-	return &Address{g.editCell.row, g.editCell.col}
-}
-
 func (g *grid) AddContainer(container Container) {
 	g.container = container
+	g.listener = container
+	g.styler = container
 }
 
 func (g *grid) GetContainer() Container {
@@ -208,68 +327,42 @@ func (g *grid) GetElement() *js.Value {
 	return &g.main
 }
 
-// Convert the screen coordinates to the grid row and col.
-func (g *grid) getLocation(x, y int) (int, int) {
-	row := y / g.cellHeight
-	col := x / g.cellWidth
-	return row, col
-}
-
 // Draw the grid foreground objects.
 func (g *grid) draw() {
 	w := g.width
 	h := g.height
+	// The Sheet may have scrolled since the last draw.
+	g.scrolled()
 
-	// Clip background canvas.
-	g.ctx.Call("drawImage", g.cnv, g.sx, g.sy, w, h, 0, 0, w, h)
+	g.render(g.renderer, g.drawBackGroundPane)
+	g.placeInput()
 
-	// Draw the data cells.
-	g.ctx.Call("save")
-	for i := range g.data {
-		// Edit cell may or may not be added to data cells yet.
-		// Don't double draw.
-		if g.data[i] != g.editCell {
-			g.data[i].draw()
-		}
-	}
+	// Draw the scroll controls.
+	r := g.renderer
+	r.Save()
+	r.SetStrokeStyle("gray")
+	r.SetFillStyle("lightgray")
+	r.FillRect(0, h-g.cellHeight, g.cellWidth/2, g.cellHeight)
+	r.StrokeRect(0, h-g.cellHeight, g.cellWidth/2, g.cellHeight)
+	r.FillRect(w-g.cellWidth/2, h-g.cellHeight, g.cellWidth/2, g.cellHeight)
+	r.StrokeRect(w-g.cellWidth/2, h-g.cellHeight, g.cellWidth/2, g.cellHeight)
+	r.FillRect(w-g.cellWidth/2, 0, g.cellWidth/2, g.cellHeight)
+	r.StrokeRect(w-g.cellWidth/2, 0, g.cellWidth/2, g.cellHeight)
+	r.FillRect(w-g.cellWidth/2, h-g.cellHeight*2, g.cellWidth/2, g.cellHeight)
+	r.StrokeRect(w-g.cellWidth/2, h-g.cellHeight*2, g.cellWidth/2, g.cellHeight)
+	r.Restore()
+}
 
-	// Draw the edit cell.
-	if g.editCell != nil {
-		g.editCell.draw()
-	}
-	g.ctx.Call("restore")
-
-	// Draw the selected cells.
-	g.ctx.Call("save")
-	g.ctx.Set("lineWidth", 1)
-	for i := range g.selectedCells {
-		s := g.selectedCells[i]
-		shadowColor := "blue"
-		borderColor := "lightblue"
-		if s.editing {
-			shadowColor = "green"
-			borderColor = "lightgreen"
-		}
-		g.ctx.Set("shadowColor", shadowColor)
-		g.ctx.Set("strokeStyle", borderColor)
-		g.ctx.Set("shadowBlur", 2)
-		g.ctx.Call("strokeRect", s.x-g.x+2, s.y-g.y+2, g.cellWidth-2, g.cellHeight-2)
+// Draw the background of a pane. The main pane is clipped from the
+// background canvas. The frozen panes are small and don't scroll in
+// both directions so their grid lines are drawn directly.
+func (g *grid) drawBackGroundPane(r Renderer, i int, p pane) {
+	if c, ok := r.(*canvasRenderer); ok && i == 0 {
+		fw, fh := g.frozenSize()
+		c.ctx.Call("drawImage", g.cnv, g.sx+fw, g.sy+fh, p.w, p.h, p.vx, p.vy, p.w, p.h)
+		return
 	}
-	g.ctx.Call("restore")
-
-	// Draw the scroll controls.
-	g.ctx.Call("save")
-	g.ctx.Set("strokeStyle", "gray")
-	g.ctx.Set("fillStyle", "lightgray")
-	g.ctx.Call("fillRect", 0, h-g.cellHeight, g.cellWidth/2, g.cellHeight)
-	g.ctx.Call("strokeRect", 0, h-g.cellHeight, g.cellWidth/2, g.cellHeight)
-	g.ctx.Call("fillRect", w-g.cellWidth/2, h-g.cellHeight, g.cellWidth/2, g.cellHeight)
-	g.ctx.Call("strokeRect", w-g.cellWidth/2, h-g.cellHeight, g.cellWidth/2, g.cellHeight)
-	g.ctx.Call("fillRect", w-g.cellWidth/2, 0, g.cellWidth/2, g.cellHeight)
-	g.ctx.Call("strokeRect", w-g.cellWidth/2, 0, g.cellWidth/2, g.cellHeight)
-	g.ctx.Call("fillRect", w-g.cellWidth/2, h-g.cellHeight*2, g.cellWidth/2, g.cellHeight)
-	g.ctx.Call("strokeRect", w-g.cellWidth/2, h-g.cellHeight*2, g.cellWidth/2, g.cellHeight)
-	g.ctx.Call("restore")
+	g.drawPaneBackground(r, i, p)
 }
 
 // Move the grid's viewport.
@@ -277,7 +370,7 @@ func (g *grid) move(dx, dy int) bool {
 	// Attempting to move outside left and top boundaries.
 	if dx < 0 && g.x+dx < 0 {
 		g.x = 0
-		g.sx = 0
+		g.drawBackGround()
 		g.direction = none
 		g.scrolling = false
 		js.Global().Call("clearInterval", g.interval)
@@ -285,7 +378,7 @@ func (g *grid) move(dx, dy int) bool {
 	}
 	if dy < 0 && g.y+dy < 0 {
 		g.y = 0
-		g.sy = 0
+		g.drawBackGround()
 		g.direction = none
 		g.scrolling = false
 		js.Global().Call("clearInterval", g.interval)
@@ -294,94 +387,63 @@ func (g *grid) move(dx, dy int) bool {
 
 	g.x += dx
 	g.y += dy
+	g.scrolled()
 
-	g.sx += dx
-	g.sy += dy
+	return true
+}
 
-	// Recycle background canvas offsets.
-	if g.direction < down && g.x%g.width == 0 {
-		if g.direction == right {
-			g.sx = 0
-		} else if g.direction == left {
-			g.sx = g.width
-		}
-	}
-	if g.direction > left && g.y%g.height == 0 {
-		if g.direction == down {
-			g.sy = 0
-		} else if g.direction == up {
-			g.sy = g.height
-		}
+// Update the background canvas position after the view-port moved.
+func (g *grid) scrolled() {
+	// Recycle the background canvas when the view-port moves outside
+	// of it.
+	if g.x < g.bgX || g.x+g.width > g.bgX+g.width*2 ||
+		g.y < g.bgY || g.y+g.height > g.bgY+g.height*2 {
+		g.drawBackGround()
 	}
-
-	return true
+	g.sx = g.x - g.bgX
+	g.sy = g.y - g.bgY
 }
 
-// Convert screen coordinates to an Address.
-func (g *grid) getAddress(x, y int) (Address, int, int) {
-	var a Address
-	bx, by := getBounds(g.vcnv)
-	x -= bx
-	y -= by
-	x += g.x
-	y += g.y
-	wx, wy := getScrollCoords()
-	dx := x / g.cellWidth
-	x -= wx
-	y -= wy
-	sx := g.cellWidth * dx
-	dy := y / g.cellHeight
-	sy := g.cellHeight * dy
-	row, col := g.getLocation(sx, sy)
-	a.Row = row
-	a.Col = col
-	return a, sx, sy
-}
-
-func (g *grid) selectCellAddress(a Address, sx, sy int) *cell {
-	if s, ok := g.data[a]; ok {
-		g.selectedCells[a] = s
-		return s
+// Draw the grid lines onto the background canvas. The background is
+// twice the size of the view-port and is positioned so the view-port
+// is in its center, leaving room to scroll in any direction before
+// it needs to be drawn again.
+func (g *grid) drawBackGround() {
+	g.bgX = g.x - g.width/2
+	if g.bgX < 0 {
+		g.bgX = 0
 	}
-	if s, ok := g.selectedCells[a]; ok {
-		return s
+	g.bgY = g.y - g.height/2
+	if g.bgY < 0 {
+		g.bgY = 0
 	}
-	s := cell{sx, sy, a.Row, a.Col, "", false, g}
-	g.selectedCells[a] = &s
-	return &s
+	g.sx = g.x - g.bgX
+	g.sy = g.y - g.bgY
+
+	w := g.width * 2
+	h := g.height * 2
+	r := NewCanvasRenderer(g.cnv.Call("getContext", "2d"))
+	r.SetFillStyle("white")
+	r.FillRect(0, 0, w, h)
+	g.strokeGridLines(r, g.bgX, g.bgY, w, h, 0, 0)
 }
 
-// Select a grid cell by screen coordinates.
-func (g *grid) selectCell(x, y int) *cell {
-	a, sx, sy := g.getAddress(x, y)
-	return g.selectCellAddress(a, sx, sy)
+// Convert page coordinates to an Address. A point in merged cells is
+// the Address of the first cell of the merge.
+func (g *grid) getAddress(x, y int) Address {
+	return g.addressAt(g.pageToView(x, y))
 }
 
-// Convert row and col values to screen coordinates.
-func (g *grid) addressToCoords(row, col int) (int, int) {
-	x := col * g.cellWidth
-	y := row * g.cellHeight
-
-	return x, y
+// Convert page coordinates to view-port coordinates.
+func (g *grid) pageToView(x, y int) (int, int) {
+	bx, by := getBounds(g.vcnv)
+	wx, wy := getScrollCoords()
+	return x - bx - wx, y - by - wy
 }
 
-// Add a value to the cell at the Address of row and col of the grid.
-func (g *grid) addData(row, col int, value string) *cell {
-	var a *cell
-	if c, ok := g.data[Address{row, col}]; ok {
-		c.value = value
-		g.data[Address{row, col}] = c
-		a = c
-	} else {
-		x, y := g.addressToCoords(row, col)
-		c := cell{x, y, row, col, value, false, g}
-		g.data[Address{row, col}] = &c
-		a = &c
-	}
-	if g.container != nil {
-		g.container.AddCell(a)
-	}
-	return a
+// Convert page coordinates to grid coordinates.
+func (g *grid) gridCoords(x, y int) (int, int) {
+	return g.viewToGrid(g.pageToView(x, y))
 }
 
 func NewGrid(obj GridObj) Grid {
@@ -390,11 +452,18 @@ func NewGrid(obj GridObj) Grid {
 	ctx, vcnv := createView(obj.width, obj.height, main)
 	ApplyCss(&vcnv, obj.class)
 
-	cnv := createBackGround(obj.width, obj.height, obj.cellWidth, obj.cellHeight)
+	cnv := createBackGround(obj.width, obj.height)
+	input := createInput(main)
 
-	g := grid{obj.class, 0, 0, 0, 0, obj.width, obj.height, vcnv, cnv, ctx,
-	main, map[Address]*cell{}, map[Address]*cell{}, obj.cellWidth, obj.cellHeight,
-	-1, js.Value{}, obj.speed, nil, false, false, false, 0, 0, nil}
+	g := grid{NewSheet(obj.width, obj.height, obj.cellWidth, obj.cellHeight),
+		obj.class, 0, 0, vcnv, cnv, ctx, main, obj.cellWidth, obj.cellHeight,
+		-1, js.Value{}, obj.speed, false, false, false, 0, 0, nil, 0, 0, nil, false, input,
+		NewCanvasRenderer(ctx)}
+	if obj.headers {
+		g.headerWidth = rowHeaderWidth
+		g.headerHeight = obj.cellHeight
+	}
+	g.drawBackGround()
 
 	grids[obj.id] = &g
 
@@ -479,10 +548,41 @@ func NewGrid(obj GridObj) Grid {
 			return nil
 		}
 
-		// Remove all selections.
-		g.selectedCells = map[Address]*cell{}
-		g.editCell = nil
-		g.selectCell(x, y)
+		// Start resizing if a column or row border was clicked.
+		if r, ok := g.borderAt(x, y); ok {
+			g.resizing = &r
+			return nil
+		}
+
+		// Clicking a header selects the whole column or row.
+		if column, i, ok := g.headerAt(x, y); ok {
+			if column {
+				g.selectColumn(i)
+			} else {
+				g.selectRow(i)
+			}
+			g.draw()
+			return nil
+		}
+		if g.inHeaders(x, y) {
+			return nil
+		}
+
+		// Shift extends the selection to the cell, Ctrl or Cmd adds a
+		// new range, otherwise the cell replaces the selection.
+		a := g.getAddress(x, y)
+		if c := g.editCell; c != nil && a == (Address{c.row, c.col}) {
+			return nil
+		}
+		g.commitEdit()
+		switch {
+		case e.Get("shiftKey").Bool():
+			g.selection.extend(a)
+		case e.Get("ctrlKey").Bool() || e.Get("metaKey").Bool():
+			g.selection.add(a)
+		default:
+			g.selection.set(a)
+		}
 		g.mouseDown = true
 		g.Draw()
 		return nil
@@ -494,6 +594,10 @@ func NewGrid(obj GridObj) Grid {
 		js.Global().Call("clearInterval", g.interval)
 		g.direction = none
 		g.mouseDown = false
+		if g.resizing != nil {
+			g.resized(g.resizing.column, g.resizing.index)
+			g.resizing = nil
+		}
 		return nil
 	})
 
@@ -502,85 +606,80 @@ func NewGrid(obj GridObj) Grid {
 		e := args[0]
 		x := e.Get("pageX").Int()
 		y := e.Get("pageY").Int()
-		c := g.selectCell(x, y)
-		c.editing = true
-		if g.editCell != nil {
-			g.editCell.editing = false
+
+		// Double clicking a column or row border fits it to its contents.
+		if r, ok := g.borderAt(x, y); ok {
+			if r.column {
+				g.autoFitColumn(r.index)
+			} else {
+				g.autoFitRow(r.index)
+			}
+			return nil
 		}
-		g.editCell = c
+		if g.inHeaders(x, y) {
+			return nil
+		}
+
+		a := g.getAddress(x, y)
+		if c := g.editCell; c != nil && a == (Address{c.row, c.col}) {
+			return nil
+		}
+		g.commitEdit()
+		g.selection.set(a)
+		g.startEdit(true)
 		g.Draw()
 		return nil
 	})
 
-	// Handle keyboard input. Used for cell editing.
-	editing := false
+	// Handle keyboard input for editing and moving the active cell.
 	keyDownCb := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		e := args[0]
+		// Keys used by an IME while composing are handled by the
+		// composition events.
+		if !g.focused || e.Get("isComposing").Bool() || e.Get("keyCode").Int() == 229 {
+			return nil
+		}
 		c := e.Get("key").String()
-		if g.active && !g.scrolling {
-			switch c {
-			case "ArrowRight":
-				if g.move(5, 0) {
-					g.scrolling = true
-					g.direction = right
-					g.interval = js.Global().Call("setInterval", moveCb, g.speed)
-				}
-				return nil
-			case "ArrowLeft":
-				if g.move(-5, 0) {
-					g.scrolling = true
-					g.direction = left
-					g.interval = js.Global().Call("setInterval", moveCb, g.speed)
-				}
-				return nil
-			case "ArrowDown":
-				if g.move(0, 5) {
-					g.scrolling = true
-					g.direction = down
-					g.interval = js.Global().Call("setInterval", moveCb, g.speed)
-				}
+		shift := e.Get("shiftKey").Bool()
+		// AltGr is reported as Ctrl+Alt on Windows, it isn't a shortcut.
+		ctrl := e.Get("ctrlKey").Bool() && !e.Get("altKey").Bool() || e.Get("metaKey").Bool()
+
+		// Undo and redo with Ctrl, or Cmd on mac. Other shortcuts are
+		// left to the browser and are not typed into the cell.
+		if ctrl {
+			switch strings.ToLower(c) {
+			case "z", "y":
+				g.undoKey(shift || strings.ToLower(c) == "y")
+				e.Call("preventDefault")
+				g.Draw()
 				return nil
-			case "ArrowUp":
-				if g.move(0, -5) {
-					g.scrolling = true
-					g.direction = up
-					g.interval = js.Global().Call("setInterval", moveCb, g.speed)
-				}
+			}
+			if utf8.RuneCountInString(c) == 1 {
 				return nil
 			}
 		}
 		if g.editCell != nil {
-			e.Call("preventDefault")
-			ec := g.editCell
-			if c == "Tab" {
-				delete(g.selectedCells, Address{ec.row, ec.col})
-				g.AddData(ec.row, ec.col, ec.value)
-				if g.container != nil {
-					g.container.AddCellsDone()
-				}
-				ec.editing = false
-				g.editCell = nil
-				editing = false
-			} else if c == "Backspace" {
-				if len(g.editCell.value) > 0 {
-					g.editCell.value = g.editCell.value[:len(g.editCell.value)-1]
-				}
-			} else if len(c) == 1 {
-				if g.editCell.editing {
-					// If not editing then this is the first
-					// letter. Clear the existing cell contents.
-					if !editing {
-						g.editCell.value = ""
-					}
-					editing = true
-					g.editCell.value += c
-				} else {
-					g.editCell.value = c
-					g.editCell.editing = true
-				}
+			if g.editKey(c, shift) {
+				e.Call("preventDefault")
+				g.Draw()
 			}
+			return nil
 		}
 
+		// F2 edits the active cell, typing replaces its value and
+		// Delete or Backspace clear the selected cells.
+		switch {
+		case c == "F2":
+			g.startEdit(true)
+		case c == "Delete" || c == "Backspace":
+			g.clearSelected()
+		case utf8.RuneCountInString(c) == 1 && !ctrl:
+			g.startEdit(false)
+			g.editor.insert(c)
+		case !g.navigate(c, shift, ctrl):
+			return nil
+		}
+		e.Call("preventDefault")
 		g.Draw()
 		return nil
 	})
@@ -603,17 +702,115 @@ func NewGrid(obj GridObj) Grid {
 		g.direction = none
 		g.scrolling = false
 		g.active = false
+		if g.resizing != nil {
+			g.resized(g.resizing.column, g.resizing.index)
+			g.resizing = nil
+		}
+		return nil
+	})
+
+	// Track whether the grid has the focus for keyboard shortcuts.
+	focusCb := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		e := args[0]
+		g.focused = e.Get("target").Equal(vcnv)
+		if g.focused {
+			// Keep the focus on the hidden input so text can be
+			// entered with an IME.
+			e.Call("preventDefault")
+			g.input.Call("focus", map[string]interface{}{"preventScroll": true})
+		}
+		return nil
+	})
+
+	// Clipboard events are handled while the grid has the focus. While
+	// editing they apply to the editor text.
+	clipboardCb := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		e := args[0]
+		if !g.focused {
+			return nil
+		}
+		data := e.Get("clipboardData")
+		typ := e.Get("type").String()
+		if g.editCell != nil {
+			if typ == "paste" {
+				g.pasteEdit(data.Call("getData", "text/plain").String())
+			} else {
+				data.Call("setData", "text/plain", g.editor.selected())
+				if typ == "cut" {
+					g.editor.insert("")
+				}
+			}
+			e.Call("preventDefault")
+			g.Draw()
+			return nil
+		}
+		handled := false
+		switch typ {
+		case "copy":
+			handled = g.copySelection(data)
+		case "cut":
+			handled = g.cutSelection(data)
+		case "paste":
+			handled = g.paste(data)
+		}
+		if handled {
+			e.Call("preventDefault")
+			g.Draw()
+		}
+		return nil
+	})
+
+	// IME composition on the hidden input. The text being composed is
+	// drawn in the edit cell until the composition ends.
+	compositionCb := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		e := args[0]
+		switch e.Get("type").String() {
+		case "compositionstart":
+			g.startComposition()
+		case "compositionupdate":
+			g.updateComposition(e.Get("data").String())
+		case "compositionend":
+			g.endComposition(e.Get("data").String())
+			g.input.Set("value", "")
+		}
+		g.Draw()
+		return nil
+	})
+
+	// Text entered into the hidden input without a key down, such as
+	// from a virtual keyboard.
+	inputCb := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if args[0].Get("isComposing").Bool() {
+			return nil
+		}
+		s := g.input.Get("value").String()
+		if s == "" {
+			return nil
+		}
+		g.input.Set("value", "")
+		if g.editCell == nil {
+			g.startEdit(false)
+		}
+		g.editor.insert(s)
+		g.Draw()
 		return nil
 	})
 
 	mouseMoveCb := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		e := args[0]
+		x := e.Get("pageX").Int()
+		y := e.Get("pageY").Int()
+		if g.resizing != nil {
+			g.resizeTo(g.resizing, x, y)
+			return nil
+		}
+		if !g.mouseDown {
+			g.updateCursor(x, y)
+		}
 		if g.active && g.mouseDown {
-			e := args[0]
-			x := e.Get("pageX").Int()
-			y := e.Get("pageY").Int()
-			a, _, _ := g.getAddress(x, y)
-			if _, ok := g.selectedCells[a]; !ok {
-				g.selectCell(x, y)
+			a := g.getAddress(x, y)
+			if r, _ := g.selection.current(); r != NewRange(g.selection.anchor, a) {
+				g.selection.extend(a)
 				g.Draw()
 			}
 		}
@@ -652,6 +849,14 @@ func NewGrid(obj GridObj) Grid {
 	vcnv.Call("addEventListener", "mouseenter", mouseEnterCb)
 	vcnv.Call("addEventListener", "mouseleave", mouseLeaveCb)
 	vcnv.Call("addEventListener", "mousemove", mouseMoveCb)
+	input.Call("addEventListener", "compositionstart", compositionCb)
+	input.Call("addEventListener", "compositionupdate", compositionCb)
+	input.Call("addEventListener", "compositionend", compositionCb)
+	input.Call("addEventListener", "input", inputCb)
+	js.Global().Get("document").Call("addEventListener", "mousedown", focusCb)
+	js.Global().Get("document").Call("addEventListener", "copy", clipboardCb)
+	js.Global().Get("document").Call("addEventListener", "cut", clipboardCb)
+	js.Global().Get("document").Call("addEventListener", "paste", clipboardCb)
 	js.Global().Get("document").Call("addEventListener", "scroll", scrollCb)
 	js.Global().Get("document").Call("addEventListener", "keydown", keyDownCb)
 	js.Global().Get("document").Call("addEventListener", "keyup", keyUpCb)
@@ -0,0 +1,167 @@
+package grid
+
+import (
+	"strconv"
+)
+
+// The width of the row header gutter.
+const rowHeaderWidth = 50
+
+// Set the label shown in a column header. An empty label restores the
+// default letter name.
+func (sh *Sheet) SetColumnHeader(col int, label string) {
+	if label == "" {
+		delete(sh.colHeaders, col)
+	} else {
+		sh.colHeaders[col] = label
+	}
+}
+
+// Set the label shown in a row header. An empty label restores the
+// default row number.
+func (sh *Sheet) SetRowHeader(row int, label string) {
+	if label == "" {
+		delete(sh.rowHeaders, row)
+	} else {
+		sh.rowHeaders[row] = label
+	}
+}
+
+// The change of a column or row header label.
+type headerEdit struct {
+	rows     bool
+	i        int
+	old, new string
+}
+
+func (e headerEdit) undo(sh *Sheet) {
+	sh.setHeader(e.rows, e.i, e.old)
+}
+
+func (e headerEdit) redo(sh *Sheet) {
+	sh.setHeader(e.rows, e.i, e.new)
+}
+
+func (sh *Sheet) setHeader(rows bool, i int, label string) {
+	if rows {
+		sh.SetRowHeader(i, label)
+	} else {
+		sh.SetColumnHeader(i, label)
+	}
+}
+
+func (sh *Sheet) columnHeader(col int) string {
+	if label, ok := sh.colHeaders[col]; ok {
+		return label
+	}
+	return ColumnName(col)
+}
+
+func (sh *Sheet) rowHeader(row int) string {
+	if label, ok := sh.rowHeaders[row]; ok {
+		return label
+	}
+	return strconv.Itoa(row + 1)
+}
+
+// Select all cells of a column, from the first row to the last visible
+// or populated row.
+func (sh *Sheet) selectColumn(col int) {
+	sh.commitEdit()
+	_, last := sh.visibleRows()
+	for a := range sh.data {
+		if a.Col == col && a.Row > last {
+			last = a.Row
+		}
+	}
+	sh.selection.setRange(Range{Address{0, col}, Address{last, col}})
+}
+
+// Select all cells of a row, from the first column to the last visible
+// or populated column.
+func (sh *Sheet) selectRow(row int) {
+	sh.commitEdit()
+	_, last := sh.visibleCols()
+	for a := range sh.data {
+		if a.Row == row && a.Col > last {
+			last = a.Col
+		}
+	}
+	sh.selection.setRange(Range{Address{row, 0}, Address{row, last}})
+}
+
+// Draw the column header strip and the row header gutter. The headers
+// scroll with the view-port but stay at the top and left edges.
+func (sh *Sheet) drawHeaders(r Renderer) {
+	if sh.headerWidth == 0 && sh.headerHeight == 0 {
+		return
+	}
+	r.Save()
+	r.SetFont("13px arial")
+	r.SetStrokeStyle("lightgray")
+	r.SetLineWidth(1)
+
+	fw, fh := sh.frozenSize()
+	hw, hh := sh.headerWidth, sh.headerHeight
+
+	// Column headers. The scrolling columns are clipped to the right
+	// of the frozen ones.
+	first, last := sh.visibleCols()
+	r.Save()
+	r.Clip(hw+fw, 0, sh.width-hw-fw, hh)
+	for col := first; col <= last; col++ {
+		sh.drawColumnHeader(r, col, sh.selection.hasCol(col))
+	}
+	r.Restore()
+	r.Save()
+	r.Clip(hw, 0, fw, hh)
+	for col := 0; col < sh.frozenCols; col++ {
+		sh.drawColumnHeader(r, col, sh.selection.hasCol(col))
+	}
+	r.Restore()
+
+	// Row headers. The scrolling rows are clipped below the frozen ones.
+	first, last = sh.visibleRows()
+	r.Save()
+	r.Clip(0, hh+fh, hw, sh.height-hh-fh)
+	for row := first; row <= last; row++ {
+		sh.drawRowHeader(r, row, sh.selection.hasRow(row))
+	}
+	r.Restore()
+	r.Save()
+	r.Clip(0, hh, hw, fh)
+	for row := 0; row < sh.frozenRows; row++ {
+		sh.drawRowHeader(r, row, sh.selection.hasRow(row))
+	}
+	r.Restore()
+
+	// The corner above the row headers.
+	sh.drawHeader(r, "", false, 0, 0, sh.headerWidth, sh.headerHeight)
+	r.Restore()
+}
+
+func (sh *Sheet) drawColumnHeader(r Renderer, col int, selected bool) {
+	x, _ := sh.toView(sh.cols.offset(col), 0)
+	sh.drawHeader(r, sh.columnHeader(col), selected, x, 0, sh.cols.sizeOf(col), sh.headerHeight)
+}
+
+func (sh *Sheet) drawRowHeader(r Renderer, row int, selected bool) {
+	_, y := sh.toView(0, sh.rows.offset(row))
+	sh.drawHeader(r, sh.rowHeader(row), selected, 0, y, sh.headerWidth, sh.rows.sizeOf(row))
+}
+
+// Draw a single header cell. The label is centered and cut to the
+// width of the header.
+func (sh *Sheet) drawHeader(r Renderer, label string, selected bool, x, y, w, h int) {
+	fill := "#f3f3f3"
+	if selected {
+		fill = "#dde6f5"
+	}
+	r.SetFillStyle(fill)
+	r.FillRect(x, y, w, h)
+	r.StrokeRect(x, y, w, h)
+	if w > 0 && h > 0 {
+		r.SetFillStyle("dimgray")
+		r.FillText(fitText(r, label, w), x+w/2, y+(h-r.LineHeight())/2, AlignCenter)
+	}
+}
@@ -0,0 +1,22 @@
+package grid
+
+// Find the column or row header at the page coordinates. Returns
+// false if the coordinates are not over a header.
+func (g *grid) headerAt(x, y int) (column bool, index int, ok bool) {
+	vx, vy := g.pageToView(x, y)
+	gx, gy := g.gridCoords(x, y)
+	switch {
+	case vy < g.headerHeight && vx >= g.headerWidth:
+		return true, g.cols.index(gx), true
+	case vx < g.headerWidth && vy >= g.headerHeight:
+		return false, g.rows.index(gy), true
+	}
+	return false, 0, false
+}
+
+// Check if the page coordinates are over the headers, including the
+// corner.
+func (g *grid) inHeaders(x, y int) bool {
+	vx, vy := g.pageToView(x, y)
+	return vx < g.headerWidth || vy < g.headerHeight
+}
@@ -0,0 +1,239 @@
+package grid
+
+// The maximum number of undoable steps kept.
+const maxHistory = 100
+
+// A reversible change to the grid.
+type command interface {
+	undo(sh *Sheet)
+	redo(sh *Sheet)
+}
+
+// The change of the raw value of a cell. A cell that doesn't exist
+// before or after the change is flagged so undo and redo can remove it.
+type valueChange struct {
+	a            Address
+	old, new     string
+	oldOk, newOk bool
+}
+
+// A command that changes the values of one or more cells.
+type cellEdit []valueChange
+
+func (e cellEdit) undo(sh *Sheet) {
+	values := map[Address]*string{}
+	for i := len(e) - 1; i >= 0; i-- {
+		c := e[i]
+		values[c.a] = nil
+		if c.oldOk {
+			values[c.a] = &e[i].old
+		}
+	}
+	sh.applyValues(values)
+}
+
+func (e cellEdit) redo(sh *Sheet) {
+	values := map[Address]*string{}
+	for i, c := range e {
+		values[c.a] = nil
+		if c.newOk {
+			values[c.a] = &e[i].new
+		}
+	}
+	sh.applyValues(values)
+}
+
+// Commands undone and redone together, undone in reverse order.
+type commandGroup []command
+
+func (g commandGroup) undo(sh *Sheet) {
+	for i := len(g) - 1; i >= 0; i-- {
+		g[i].undo(sh)
+	}
+}
+
+func (g commandGroup) redo(sh *Sheet) {
+	for _, c := range g {
+		c.redo(sh)
+	}
+}
+
+// The undo and redo stacks of a grid. Changes recorded between begin
+// and end are grouped into a single step.
+type history struct {
+	undo     []command
+	redo     []command
+	group    commandGroup
+	depth    int
+	applying bool // undoing or redoing, don't record
+}
+
+// Start grouping changes into a single undoable step. Groups can be
+// nested, the step is complete when the outermost group ends.
+func (h *history) begin() {
+	h.depth++
+}
+
+func (h *history) end() {
+	if h.depth == 0 {
+		return
+	}
+	h.depth--
+	if h.depth == 0 && len(h.group) > 0 {
+		if len(h.group) == 1 {
+			h.push(h.group[0])
+		} else {
+			h.push(h.group)
+		}
+		h.group = nil
+	}
+}
+
+// Record a command that was applied, as its own step or as part of the
+// current group.
+func (h *history) add(c command) {
+	if h.applying {
+		return
+	}
+	if h.depth > 0 {
+		h.group = append(h.group, c)
+		return
+	}
+	h.push(c)
+}
+
+func (h *history) push(c command) {
+	h.undo = append(h.undo, c)
+	if len(h.undo) > maxHistory {
+		h.undo = h.undo[len(h.undo)-maxHistory:]
+	}
+	h.redo = nil
+}
+
+// Record the change of a cell value. A missing cell is the same as an
+// empty one so creating an empty cell isn't an undoable step.
+func (h *history) record(c valueChange) {
+	if h.applying || c.old == c.new {
+		return
+	}
+	// Value changes in a row are one cellEdit so they are recalculated
+	// together.
+	if n := len(h.group); h.depth > 0 && n > 0 {
+		if e, ok := h.group[n-1].(cellEdit); ok {
+			h.group[n-1] = append(e, c)
+			return
+		}
+	}
+	h.add(cellEdit{c})
+}
+
+// Record the differences between a snapshot of the cell values taken
+// before a change and the current values as a single step.
+func (sh *Sheet) recordSnapshot(before map[Address]string) {
+	sh.history.begin()
+	for a, old := range before {
+		c, ok := sh.data[a]
+		if !ok {
+			sh.history.record(valueChange{a, old, "", true, false})
+		} else if c.value != old {
+			sh.history.record(valueChange{a, old, c.value, true, true})
+		}
+	}
+	for a, c := range sh.data {
+		if _, ok := before[a]; !ok {
+			sh.history.record(valueChange{a, "", c.value, false, true})
+		}
+	}
+	sh.history.end()
+}
+
+// A snapshot of the raw values of every cell.
+func (sh *Sheet) snapshot() map[Address]string {
+	values := map[Address]string{}
+	for a, c := range sh.data {
+		values[a] = c.value
+	}
+	return values
+}
+
+// Set or remove, for nil values, the raw values of cells without
+// recording history. The formulas are recalculated once for all of
+// the changes.
+func (sh *Sheet) applyValues(values map[Address]*string) {
+	changed := []Address{}
+	for a, v := range values {
+		c, ok := sh.data[a]
+		if v == nil {
+			if ok {
+				delete(sh.data, a)
+				sh.index.remove(a)
+				sh.deps.remove(a)
+				changed = append(changed, a)
+			}
+			continue
+		}
+		if !ok {
+			c = &cell{row: a.Row, col: a.Col, sheet: sh}
+			sh.data[a] = c
+			sh.index.add(a)
+		}
+		c.value = *v
+		sh.parse(c)
+		changed = append(changed, a)
+		if sh.listener != nil {
+			sh.listener.AddCell(c)
+		}
+	}
+	sh.recalc(changed...)
+	if sh.listener != nil {
+		sh.listener.AddCellsDone()
+	}
+}
+
+// Undo the last change.
+func (sh *Sheet) Undo() {
+	h := &sh.history
+	if len(h.undo) == 0 {
+		return
+	}
+	sh.cancelEdit()
+	c := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.applying = true
+	c.undo(sh)
+	h.applying = false
+	h.redo = append(h.redo, c)
+}
+
+// Redo the last undone change.
+func (sh *Sheet) Redo() {
+	h := &sh.history
+	if len(h.redo) == 0 {
+		return
+	}
+	sh.cancelEdit()
+	c := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.applying = true
+	c.redo(sh)
+	h.applying = false
+	h.undo = append(h.undo, c)
+}
+
+func (sh *Sheet) CanUndo() bool {
+	return len(sh.history.undo) > 0
+}
+
+func (sh *Sheet) CanRedo() bool {
+	return len(sh.history.redo) > 0
+}
+
+// Group the following changes into a single undoable step until
+// EndUpdate is called.
+func (sh *Sheet) BeginUpdate() {
+	sh.history.begin()
+}
+
+func (sh *Sheet) EndUpdate() {
+	sh.history.end()
+}
@@ -0,0 +1,257 @@
+package grid
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// A Renderer drawing onto an RGBA image, for rendering a Sheet without a
+// browser. Text is drawn with a small bitmap font scaled to the font
+// size, lines are not anti-aliased and shadows are not drawn, so a
+// Sheet renders to the same pixels on every platform.
+type ImageRenderer struct {
+	img   *image.RGBA
+	state imageState
+	saved []imageState
+	path  [][]point // the subpaths of the current path
+}
+
+// The drawing state saved and restored by Save and Restore.
+type imageState struct {
+	clip      image.Rectangle
+	fillStyle string
+	fill      color.NRGBA
+	stroke    color.NRGBA
+	lineWidth float64
+	dash      []float64
+	font      bitmapFont
+}
+
+type point struct {
+	x, y float64
+}
+
+// Create a Renderer drawing onto img.
+func NewImageRenderer(img *image.RGBA) *ImageRenderer {
+	black := color.NRGBA{0, 0, 0, 255}
+	return &ImageRenderer{img: img, state: imageState{
+		clip:      img.Bounds(),
+		fillStyle: "#000000",
+		fill:      black,
+		stroke:    black,
+		lineWidth: 1,
+		font:      parseFont("").bitmap(),
+	}}
+}
+
+// The image drawn onto.
+func (r *ImageRenderer) Image() *image.RGBA {
+	return r.img
+}
+
+func (r *ImageRenderer) Save() {
+	r.saved = append(r.saved, r.state)
+}
+
+func (r *ImageRenderer) Restore() {
+	if n := len(r.saved); n > 0 {
+		r.state = r.saved[n-1]
+		r.saved = r.saved[:n-1]
+	}
+}
+
+func (r *ImageRenderer) Clip(x, y, w, h int) {
+	r.state.clip = r.state.clip.Intersect(image.Rect(x, y, x+w, y+h))
+}
+
+func (r *ImageRenderer) SetFont(font string) {
+	r.state.font = parseFont(font).bitmap()
+}
+
+// Colors that are not recognized are ignored, like the canvas does.
+func (r *ImageRenderer) SetFillStyle(c string) {
+	if nc, ok := parseColor(c); ok {
+		r.state.fillStyle = c
+		r.state.fill = nc
+	}
+}
+
+func (r *ImageRenderer) FillStyle() string {
+	return r.state.fillStyle
+}
+
+func (r *ImageRenderer) SetStrokeStyle(c string) {
+	if nc, ok := parseColor(c); ok {
+		r.state.stroke = nc
+	}
+}
+
+func (r *ImageRenderer) SetLineWidth(width float64) {
+	if width > 0 {
+		r.state.lineWidth = width
+	}
+}
+
+func (r *ImageRenderer) SetLineDash(dash []float64) {
+	r.state.dash = append([]float64(nil), dash...)
+}
+
+// Shadows are not drawn.
+func (r *ImageRenderer) SetShadow(color string, blur float64) {
+}
+
+func (r *ImageRenderer) FillRect(x, y, w, h int) {
+	r.fill(image.Rect(x, y, x+w, y+h), r.state.fill, 1)
+}
+
+func (r *ImageRenderer) StrokeRect(x, y, w, h int) {
+	x0, y0 := float64(x), float64(y)
+	x1, y1 := float64(x+w), float64(y+h)
+	r.strokePath([]point{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}, {x0, y0}}, true)
+}
+
+func (r *ImageRenderer) BeginPath() {
+	r.path = nil
+}
+
+func (r *ImageRenderer) MoveTo(x, y float64) {
+	r.path = append(r.path, []point{{x, y}})
+}
+
+func (r *ImageRenderer) LineTo(x, y float64) {
+	if len(r.path) == 0 {
+		r.MoveTo(x, y)
+		return
+	}
+	sub := &r.path[len(r.path)-1]
+	*sub = append(*sub, point{x, y})
+}
+
+func (r *ImageRenderer) Stroke() {
+	for _, sub := range r.path {
+		r.strokePath(sub, false)
+	}
+}
+
+func (r *ImageRenderer) FillText(text string, x, y int, align HAlign) {
+	f := r.state.font
+	switch align {
+	case AlignCenter:
+		x -= f.width(text) / 2
+	case AlignRight:
+		x -= f.width(text)
+	}
+	f.pixels(text, x, y, func(x, y, size int) {
+		r.fill(image.Rect(x, y, x+size, y+size), r.state.fill, 1)
+	})
+}
+
+func (r *ImageRenderer) MeasureText(text string) float64 {
+	return float64(r.state.font.width(text))
+}
+
+func (r *ImageRenderer) LineHeight() int {
+	return r.state.font.lineHeight()
+}
+
+// Blend a color over a rectangle inside the clip region. Coverage
+// below 1 draws the color partly transparent.
+func (r *ImageRenderer) fill(rect image.Rectangle, c color.NRGBA, coverage float64) {
+	rect = rect.Intersect(r.state.clip)
+	a := float64(c.A) * coverage
+	if rect.Empty() || a < 0.5 {
+		return
+	}
+	c.A = uint8(math.Min(a+0.5, 255))
+	draw.Draw(r.img, rect, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// Stroke the lines of a subpath with the line width and dash of the
+// state. The dash pattern runs on over the corners of the subpath. A
+// closed subpath has square corners, the ends of an open one are cut
+// off at its points.
+func (r *ImageRenderer) strokePath(points []point, closed bool) {
+	dash := r.state.dash
+	for _, d := range dash {
+		if d <= 0 {
+			dash = nil
+			break
+		}
+	}
+	di, left := 0, 0.0 // dash index and length left of it
+	if len(dash) > 0 {
+		left = dash[0]
+	}
+	for i := 1; i < len(points); i++ {
+		p, q := points[i-1], points[i]
+		length := math.Hypot(q.x-p.x, q.y-p.y)
+		if length == 0 {
+			continue
+		}
+		ext := 0.0
+		if closed {
+			ext = r.state.lineWidth / 2
+		}
+		if len(dash) == 0 {
+			r.line(p, q, -ext, length+ext)
+			continue
+		}
+		for pos := 0.0; pos < length; {
+			end := math.Min(pos+left, length)
+			if di%2 == 0 {
+				r.line(p, q, pos, end)
+			}
+			left -= end - pos
+			pos = end
+			if left <= 0 {
+				di = (di + 1) % len(dash)
+				left = dash[di]
+			}
+		}
+	}
+}
+
+// Draw the part of the line from p to q between the distances from and
+// to along it. Thin lines are drawn partly transparent.
+func (r *ImageRenderer) line(p, q point, from, to float64) {
+	w := r.state.lineWidth
+	coverage := math.Min(w, 1)
+	length := math.Hypot(q.x-p.x, q.y-p.y)
+	dx, dy := (q.x-p.x)/length, (q.y-p.y)/length
+	a := point{p.x + dx*from, p.y + dy*from}
+	b := point{p.x + dx*to, p.y + dy*to}
+	switch {
+	case p.y == q.y:
+		y0, y1 := span(p.y, w)
+		x0, x1 := round(math.Min(a.x, b.x)), round(math.Max(a.x, b.x))
+		r.fill(image.Rect(x0, y0, x1, y1), r.state.stroke, coverage)
+	case p.x == q.x:
+		x0, x1 := span(p.x, w)
+		y0, y1 := round(math.Min(a.y, b.y)), round(math.Max(a.y, b.y))
+		r.fill(image.Rect(x0, y0, x1, y1), r.state.stroke, coverage)
+	default:
+		// Other lines are drawn as squares a pixel apart.
+		size := int(math.Max(math.Round(w), 1))
+		for t := from; t <= to; t++ {
+			x := round(p.x+dx*t) - size/2
+			y := round(p.y+dy*t) - size/2
+			r.fill(image.Rect(x, y, x+size, y+size), r.state.stroke, coverage)
+		}
+	}
+}
+
+// The pixels covered by a line of width w centered on c, at least one.
+func span(c, w float64) (int, int) {
+	lo := int(math.Ceil(c - w/2 - 0.5))
+	hi := int(math.Ceil(c + w/2 - 0.5))
+	if hi <= lo {
+		lo = hi - 1
+	}
+	return lo, hi
+}
+
+func round(f float64) int {
+	return int(math.Floor(f + 0.5))
+}
@@ -0,0 +1,53 @@
+package grid
+
+// The size in rows and columns of the tiles of the cell index.
+const (
+	tileRows = 32
+	tileCols = 16
+)
+
+type tile struct {
+	row, col int
+}
+
+// A spatial index of the populated cells. Addresses are bucketed into
+// tiles so the cells inside a range, such as the view-port, can be
+// found without visiting every cell of the grid.
+type cellIndex map[tile]map[Address]bool
+
+func tileOf(a Address) tile {
+	return tile{a.Row / tileRows, a.Col / tileCols}
+}
+
+func (x cellIndex) add(a Address) {
+	t := tileOf(a)
+	if x[t] == nil {
+		x[t] = map[Address]bool{}
+	}
+	x[t][a] = true
+}
+
+func (x cellIndex) remove(a Address) {
+	t := tileOf(a)
+	delete(x[t], a)
+	if len(x[t]) == 0 {
+		delete(x, t)
+	}
+}
+
+// The indexed addresses inside the range.
+func (x cellIndex) query(r Range) []Address {
+	addresses := []Address{}
+	from := tileOf(r.From)
+	to := tileOf(r.To)
+	for row := from.row; row <= to.row; row++ {
+		for col := from.col; col <= to.col; col++ {
+			for a := range x[tile{row, col}] {
+				if r.Contains(a) {
+					addresses = append(addresses, a)
+				}
+			}
+		}
+	}
+	return addresses
+}
@@ -1,7 +1,12 @@
+//go:build js && wasm
+// +build js,wasm
+
 package grid
 
 import (
+	"bytes"
 	"syscall/js"
+	"time"
 )
 
 // A type representing the javaScript
@@ -15,6 +20,7 @@ type GridObj struct {
 	cellWidth  int
 	cellHeight int
 	speed      int
+	headers    bool
 }
 
 // Class to style list.
@@ -106,28 +112,18 @@ func CreateElement(typ string) js.Value {
 	return doc.Call("createElement", typ)
 }
 
-// Create the canvas that will be used as the background.
-// TODO: consider making the strokeStyle border color a setting.
-func createBackGround(width, height, cellWidth, cellHeight int) js.Value {
+// Helper for dispatching a custom event with a detail object.
+func dispatchEvent(el js.Value, name string, detail map[string]interface{}) {
+	event := js.Global().Get("CustomEvent").New(name, map[string]interface{}{"detail": detail})
+	el.Call("dispatchEvent", event)
+}
+
+// Create the canvas that will be used as the background. The grid
+// lines are drawn onto it by grid.drawBackGround.
+func createBackGround(width, height int) js.Value {
 	cnv := CreateElement("canvas")
 	cnv.Set("width", width*2)
 	cnv.Set("height", height*2)
-	ctx := cnv.Call("getContext", "2d")
-	ctx.Set("fillStyle", "white")
-	ctx.Call("fillRect", 0, 0, width*2, height*2)
-	ctx.Set("lineWidth", 0.25)
-	ctx.Call("beginPath")
-	rows := height*2/cellHeight + 1
-	cols := width*2/cellWidth + 1
-	for i := 0; i < cols; i++ {
-		ctx.Call("moveTo", i*cellWidth, 0)
-		ctx.Call("lineTo", i*cellWidth, height*2)
-	}
-	for i := 0; i < rows; i++ {
-		ctx.Call("moveTo", 0, i*cellHeight)
-		ctx.Call("lineTo", width*2, i*cellHeight)
-	}
-	ctx.Call("stroke")
 	return cnv
 }
 
@@ -141,6 +137,19 @@ func createView(width, height int, main js.Value) (js.Value, js.Value) {
 	return ctx, cnv
 }
 
+// Create the hidden input that receives the text entered with an IME.
+// It is kept transparent and lets the mouse events through to the
+// view-port canvas.
+func createInput(main js.Value) js.Value {
+	input := CreateElement("input")
+	input.Call("setAttribute", "autocomplete", "off")
+	input.Set("style", "position: absolute; left: 0; top: 0; width: 1px; padding: 0; border: 0; "+
+		"opacity: 0; pointer-events: none;")
+	main.Get("style").Set("position", "relative")
+	main.Call("appendChild", input)
+	return input
+}
+
 // Create a new grid object from json.
 // Note that if the width and height are not divisible by the
 // cellWidth and cellHeight they will be adjusted.
@@ -159,22 +168,121 @@ func NewGridObj(obj js.Value) GridObj {
 	g.height = g.height / g.cellHeight * g.cellHeight
 
 	g.speed = obj.Get("scroll-speed").Int()
+
+	// Headers are shown unless turned off.
+	headers := obj.Get("headers")
+	g.headers = headers.Type() == js.TypeUndefined || headers.Truthy()
 	return g
 }
 
-// External JavaScript function to add data to a grid.
-// args: "grid id", row, col.
+// External JavaScript function to add data to a grid. Strings are
+// handled the same as text typed into a cell. Numbers, booleans and
+// Date objects are stored as typed values.
+// args: "grid id", row, col, value.
 func AddData(this js.Value, args[]js.Value) interface{} {
 	id := args[0].String()
 	row := args[1].Int()
 	col := args[2].Int()
-	value := args[3].String()
 	g := grids[id]
-	g.addData(row, col, value)
+	if args[3].Type() == js.TypeString {
+		g.addData(row, col, args[3].String())
+	} else {
+		g.addValue(row, col, jsToValue(args[3]))
+	}
 	g.Draw()
 	return nil
 }
 
+// Convert a JavaScript value to a cell value.
+func jsToValue(v js.Value) Value {
+	switch v.Type() {
+	case js.TypeNumber:
+		return NumberVal(v.Float())
+	case js.TypeBoolean:
+		return BoolVal(v.Bool())
+	case js.TypeString:
+		return ParseValue(v.String())
+	case js.TypeObject:
+		if v.InstanceOf(js.Global().Get("Date")) {
+			// Use the local date and time the user sees.
+			t := time.Date(v.Call("getFullYear").Int(), time.Month(v.Call("getMonth").Int()+1),
+				v.Call("getDate").Int(), v.Call("getHours").Int(), v.Call("getMinutes").Int(),
+				v.Call("getSeconds").Int(), v.Call("getMilliseconds").Int()*int(time.Millisecond), time.UTC)
+			return DateVal(t)
+		}
+		return TextVal(v.Call("toString").String())
+	}
+	return Value{}
+}
+
+// External JavaScript function to set the display format of a cell.
+// args: "grid id", row, col, "format".
+func SetCellFormat(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetCellFormat(args[1].Int(), args[2].Int(), args[3].String())
+	return nil
+}
+
+// External JavaScript function to set the display format of a column.
+// args: "grid id", col, "format".
+func SetColumnFormat(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetColumnFormat(args[1].Int(), args[2].String())
+	return nil
+}
+
+// External JavaScript function to set the display format of a range.
+// args: "grid id", "A1:C4", "format".
+func SetRangeFormat(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	r, ok := ParseRange(args[1].String())
+	if !ok {
+		return nil
+	}
+	g.SetRangeFormat(r, args[2].String())
+	return nil
+}
+
+// External JavaScript function to set the width of a column.
+// args: "grid id", col, width.
+func SetColumnWidth(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetColumnWidth(args[1].Int(), args[2].Int())
+	return nil
+}
+
+// External JavaScript function to set the height of a row.
+// args: "grid id", row, height.
+func SetRowHeight(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetRowHeight(args[1].Int(), args[2].Int())
+	return nil
+}
+
+// External JavaScript function to set the label of a column header.
+// args: "grid id", col, "label".
+func SetColumnHeader(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetColumnHeader(args[1].Int(), args[2].String())
+	return nil
+}
+
+// External JavaScript function to set the label of a row header.
+// args: "grid id", row, "label".
+func SetRowHeader(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetRowHeader(args[1].Int(), args[2].String())
+	return nil
+}
+
+// External JavaScript function to freeze the first rows and columns.
+// args: "grid id", rows, cols.
+func FreezePanes(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.FreezePanes(args[1].Int(), args[2].Int())
+	return nil
+}
+
 // External JavaScript function to create a new grid.
 // args: JSON object(GridObj).
 func NewGridJs(this js.Value, args[]js.Value) interface{} {
@@ -183,3 +291,430 @@ func NewGridJs(this js.Value, args[]js.Value) interface{} {
 	g.Draw()
 	return g.GetElement()
 }
+
+// External JavaScript function to undo the last change.
+// args: "grid id".
+func Undo(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.Undo()
+	return nil
+}
+
+// External JavaScript function to redo the last undone change.
+// args: "grid id".
+func Redo(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.Redo()
+	return nil
+}
+
+// External JavaScript function to check if there is a change to undo.
+// args: "grid id".
+func CanUndo(this js.Value, args []js.Value) interface{} {
+	return grids[args[0].String()].CanUndo()
+}
+
+// External JavaScript function to check if there is a change to redo.
+// args: "grid id".
+func CanRedo(this js.Value, args []js.Value) interface{} {
+	return grids[args[0].String()].CanRedo()
+}
+
+// External JavaScript function to group the following changes into a
+// single undoable step.
+// args: "grid id".
+func BeginUpdate(this js.Value, args []js.Value) interface{} {
+	grids[args[0].String()].BeginUpdate()
+	return nil
+}
+
+// External JavaScript function to end a group started by beginUpdate.
+// args: "grid id".
+func EndUpdate(this js.Value, args []js.Value) interface{} {
+	grids[args[0].String()].EndUpdate()
+	return nil
+}
+
+// External JavaScript function to get the selected ranges as an array
+// of A1 range strings.
+// args: "grid id".
+func GetSelection(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	ranges := []interface{}{}
+	for _, r := range g.GetSelection() {
+		ranges = append(ranges, r.String())
+	}
+	return ranges
+}
+
+// Convert a JavaScript object such as
+// {horizontal: "center", vertical: "middle", wrap: true} to an
+// Alignment. Missing fields are the defaults.
+func jsToAlignment(obj js.Value) Alignment {
+	align := Alignment{}
+	if v := obj.Get("horizontal"); v.Type() == js.TypeString {
+		align.Horizontal, _ = parseHAlign(v.String())
+	}
+	if v := obj.Get("vertical"); v.Type() == js.TypeString {
+		align.Vertical, _ = parseVAlign(v.String())
+	}
+	align.Wrap = obj.Get("wrap").Truthy()
+	return align
+}
+
+// External JavaScript function to set the alignment of a cell.
+// args: "grid id", row, col, {horizontal, vertical, wrap}.
+func SetCellAlignment(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetCellAlignment(args[1].Int(), args[2].Int(), jsToAlignment(args[3]))
+	return nil
+}
+
+// External JavaScript function to set the alignment of a column.
+// args: "grid id", col, {horizontal, vertical, wrap}.
+func SetColumnAlignment(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetColumnAlignment(args[1].Int(), jsToAlignment(args[2]))
+	return nil
+}
+
+// External JavaScript function to set the alignment of a range.
+// args: "grid id", "A1:C4", {horizontal, vertical, wrap}.
+func SetRangeAlignment(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	r, ok := ParseRange(args[1].String())
+	if !ok {
+		return nil
+	}
+	g.SetRangeAlignment(r, jsToAlignment(args[2]))
+	return nil
+}
+
+// Convert a JavaScript object such as
+// {background: "#eee", fontWeight: "bold", color: "red", alignment: {...},
+// borders: {bottom: {color: "black", width: 2}}} to a CellStyle. The
+// bold and italic booleans are shorthands for fontWeight and fontStyle.
+func jsToStyle(obj js.Value) CellStyle {
+	s := CellStyle{}
+	str := func(name string) string {
+		if v := obj.Get(name); v.Type() == js.TypeString {
+			return v.String()
+		}
+		return ""
+	}
+	num := func(name string) int {
+		if v := obj.Get(name); v.Type() == js.TypeNumber {
+			return v.Int()
+		}
+		return 0
+	}
+	s.Background = str("background")
+	s.FontFamily = str("fontFamily")
+	s.FontSize = num("fontSize")
+	s.FontWeight = str("fontWeight")
+	s.FontStyle = str("fontStyle")
+	s.Color = str("color")
+	s.Padding = num("padding")
+	if obj.Get("bold").Truthy() {
+		s.FontWeight = "bold"
+	}
+	if obj.Get("italic").Truthy() {
+		s.FontStyle = "italic"
+	}
+	if v := obj.Get("alignment"); v.Type() == js.TypeObject {
+		s.Alignment = jsToAlignment(v)
+	}
+	if v := obj.Get("borders"); v.Type() == js.TypeObject {
+		s.Borders = jsToBorders(v)
+	}
+	return s
+}
+
+// Convert a JavaScript object with top, right, bottom and left border
+// objects to Borders.
+func jsToBorders(obj js.Value) Borders {
+	side := func(name string) Border {
+		v := obj.Get(name)
+		if v.Type() != js.TypeObject {
+			return Border{}
+		}
+		return jsToBorder(v)
+	}
+	return Borders{side("top"), side("right"), side("bottom"), side("left")}
+}
+
+// Convert a JavaScript object such as
+// {color: "black", width: 1, dash: "dashed"} to a Border.
+func jsToBorder(obj js.Value) Border {
+	b := Border{}
+	if v := obj.Get("color"); v.Type() == js.TypeString {
+		b.Color = v.String()
+	}
+	if v := obj.Get("width"); v.Type() == js.TypeNumber {
+		b.Width = v.Int()
+	}
+	if v := obj.Get("dash"); v.Type() == js.TypeString {
+		b.Dash = v.String()
+	}
+	return b
+}
+
+// External JavaScript function to set the style of a cell.
+// args: "grid id", row, col, style object.
+func SetCellStyle(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetCellStyle(args[1].Int(), args[2].Int(), jsToStyle(args[3]))
+	return nil
+}
+
+// External JavaScript function to set the style of a row.
+// args: "grid id", row, style object.
+func SetRowStyle(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetRowStyle(args[1].Int(), jsToStyle(args[2]))
+	return nil
+}
+
+// External JavaScript function to set the style of a column.
+// args: "grid id", col, style object.
+func SetColumnStyle(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.SetColumnStyle(args[1].Int(), jsToStyle(args[2]))
+	return nil
+}
+
+// External JavaScript function to set the style of a range.
+// args: "grid id", "A1:C4", style object.
+func SetRangeStyle(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	r, ok := ParseRange(args[1].String())
+	if !ok {
+		return nil
+	}
+	g.SetRangeStyle(r, jsToStyle(args[2]))
+	return nil
+}
+
+// External JavaScript function to draw a border around a range.
+// args: "grid id", "A1:C4", {color, width, dash}.
+func OutlineRange(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	r, ok := ParseRange(args[1].String())
+	if !ok {
+		return nil
+	}
+	g.OutlineRange(r, jsToBorder(args[2]))
+	return nil
+}
+
+// External JavaScript function to draw a border on every cell of a
+// range.
+// args: "grid id", "A1:C4", {color, width, dash}.
+func SetRangeBorders(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	r, ok := ParseRange(args[1].String())
+	if !ok {
+		return nil
+	}
+	g.SetRangeBorders(r, jsToBorder(args[2]))
+	return nil
+}
+
+// External JavaScript function to remove the borders of a range.
+// args: "grid id", "A1:C4".
+func ClearRangeBorders(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	r, ok := ParseRange(args[1].String())
+	if !ok {
+		return nil
+	}
+	g.ClearRangeBorders(r)
+	return nil
+}
+
+// External JavaScript function to merge a range into a single cell.
+// args: "grid id", "A1:C4".
+func MergeCells(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	r, ok := ParseRange(args[1].String())
+	if !ok {
+		return nil
+	}
+	g.MergeCells(r)
+	return nil
+}
+
+// External JavaScript function to split the merged cells in a range.
+// args: "grid id", "A1:C4".
+func UnmergeCells(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	r, ok := ParseRange(args[1].String())
+	if !ok {
+		return nil
+	}
+	g.UnmergeCells(r)
+	return nil
+}
+
+// External JavaScript function to get the merged ranges as an array of
+// A1 range strings.
+// args: "grid id".
+func GetMerges(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	ranges := []interface{}{}
+	for _, r := range g.GetMerges() {
+		ranges = append(ranges, r.String())
+	}
+	return ranges
+}
+
+// External JavaScript function to insert rows.
+// args: "grid id", row, count.
+func AddRow(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.AddRow(args[1].Int(), args[2].Int())
+	return nil
+}
+
+// External JavaScript function to insert columns.
+// args: "grid id", col, count.
+func AddColumn(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.AddColumn(args[1].Int(), args[2].Int())
+	return nil
+}
+
+// External JavaScript function to remove rows.
+// args: "grid id", row, count.
+func DeleteRow(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.DeleteRow(args[1].Int(), args[2].Int())
+	return nil
+}
+
+// External JavaScript function to remove columns.
+// args: "grid id", col, count.
+func DeleteColumn(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	g.DeleteColumn(args[1].Int(), args[2].Int())
+	return nil
+}
+
+// Convert a JavaScript object such as
+// {delimiter: ";", encoding: "windows-1252", header: true, bom: true} to
+// CSVOptions.
+func jsToCSVOptions(obj js.Value) CSVOptions {
+	opts := CSVOptions{}
+	if obj.Type() != js.TypeObject {
+		return opts
+	}
+	if v := obj.Get("delimiter"); v.Type() == js.TypeString && v.String() != "" {
+		opts.Delimiter = []rune(v.String())[0]
+	}
+	if v := obj.Get("encoding"); v.Type() == js.TypeString {
+		opts.Encoding = v.String()
+	}
+	opts.Header = obj.Get("header").Truthy()
+	opts.BOM = obj.Get("bom").Truthy()
+	return opts
+}
+
+// The range argument of the csv functions, the populated cells if it
+// is missing.
+func jsToExportRange(g *grid, v js.Value) (Range, bool) {
+	if v.Type() != js.TypeString {
+		return g.DataRange(), true
+	}
+	return ParseRange(v.String())
+}
+
+// External JavaScript function to import CSV text into the cells as a
+// single undoable step. The data is a string, File or Blob, the
+// encoding option only applies to a File or Blob. Returns a Promise
+// for the A1 range of the imported cells.
+// args: "grid id", data, "A1" first cell, {delimiter, encoding, header}.
+func ImportCSV(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	anchor := Address{}
+	if len(args) > 2 && args[2].Type() == js.TypeString {
+		a, ok := ParseAddress(args[2].String())
+		if !ok {
+			return js.Global().Get("Promise").Call("reject",
+				js.Global().Get("Error").New("invalid cell address "+args[2].String()))
+		}
+		anchor = a
+	}
+	opts := CSVOptions{}
+	if len(args) > 3 {
+		opts = jsToCSVOptions(args[3])
+	}
+	data := args[1]
+	return newPromise(func(resolve, reject js.Value) {
+		done := func(b []byte, err error) {
+			var r Range
+			if err == nil {
+				r, err = g.ImportCSV(bytes.NewReader(b), anchor, opts)
+			}
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke(r.String())
+		}
+		if data.Type() == js.TypeString {
+			// JavaScript strings are already decoded.
+			opts.Encoding = ""
+			done([]byte(data.String()), nil)
+			return
+		}
+		readBlob(data, done)
+	})
+}
+
+// External JavaScript function to export the displayed text of a range
+// as a CSV string. The encoding and bom options are ignored.
+// args: "grid id", "A1:C4" or undefined for all values, {delimiter, header}.
+func ExportCSV(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	var rv, ov js.Value
+	if len(args) > 1 {
+		rv = args[1]
+	}
+	if len(args) > 2 {
+		ov = args[2]
+	}
+	r, ok := jsToExportRange(g, rv)
+	if !ok {
+		return nil
+	}
+	opts := jsToCSVOptions(ov)
+	opts.Encoding, opts.BOM = "", false
+	var b bytes.Buffer
+	if err := g.ExportCSV(&b, r, opts); err != nil {
+		return nil
+	}
+	return b.String()
+}
+
+// External JavaScript function to save a range as a CSV file.
+// args: "grid id", "A1:C4" or undefined for all values, "file name",
+// {delimiter, encoding, header, bom}.
+func DownloadCSV(this js.Value, args []js.Value) interface{} {
+	g := grids[args[0].String()]
+	var rv, ov js.Value
+	if len(args) > 1 {
+		rv = args[1]
+	}
+	name := "grid.csv"
+	if len(args) > 2 && args[2].Type() == js.TypeString {
+		name = args[2].String()
+	}
+	if len(args) > 3 {
+		ov = args[3]
+	}
+	r, ok := jsToExportRange(g, rv)
+	if !ok {
+		return false
+	}
+	return g.downloadCSV(r, name, jsToCSVOptions(ov)) == nil
+}
@@ -0,0 +1,95 @@
+package grid
+
+import (
+	"sort"
+)
+
+// The sizes of the columns or rows of the grid. Only sizes that differ
+// from the default are stored. The indexes with custom sizes are kept
+// sorted together with the prefix sums of their size differences, so
+// converting between an index and a pixel offset is a binary search.
+type axis struct {
+	size  int         // default size
+	sizes map[int]int // custom sizes by index
+	keys  []int       // sorted indexes with a custom size
+	sums  []int       // sums[i] is the total size difference of keys[:i]
+}
+
+func newAxis(size int) axis {
+	return axis{size, map[int]int{}, []int{}, []int{0}}
+}
+
+func (a *axis) copy() axis {
+	c := newAxis(a.size)
+	for i, s := range a.sizes {
+		c.sizes[i] = s
+	}
+	c.update()
+	return c
+}
+
+// The size of the column or row at index i.
+func (a *axis) sizeOf(i int) int {
+	if s, ok := a.sizes[i]; ok {
+		return s
+	}
+	return a.size
+}
+
+// Set the size of the column or row at index i.
+func (a *axis) set(i, size int) {
+	if size < 0 {
+		size = 0
+	}
+	if size == a.size {
+		delete(a.sizes, i)
+	} else {
+		a.sizes[i] = size
+	}
+	a.update()
+}
+
+// Rebuild the sorted keys and prefix sums.
+func (a *axis) update() {
+	a.keys = a.keys[:0]
+	for k := range a.sizes {
+		a.keys = append(a.keys, k)
+	}
+	sort.Ints(a.keys)
+	a.sums = append(a.sums[:0], 0)
+	for i, k := range a.keys {
+		a.sums = append(a.sums, a.sums[i]+a.sizes[k]-a.size)
+	}
+}
+
+// The pixel offset of the start of the column or row at index i.
+func (a *axis) offset(i int) int {
+	k := sort.SearchInts(a.keys, i)
+	return i*a.size + a.sums[k]
+}
+
+// The index of the column or row containing the pixel offset px.
+func (a *axis) index(px int) int {
+	if px < 0 {
+		return 0
+	}
+	// Find the last custom sized index that starts at or before px.
+	k := sort.Search(len(a.keys), func(j int) bool {
+		return a.keys[j]*a.size+a.sums[j] > px
+	}) - 1
+	if k < 0 {
+		if a.size == 0 {
+			return 0
+		}
+		return px / a.size
+	}
+	start := a.keys[k]*a.size + a.sums[k]
+	end := start + a.sizes[a.keys[k]]
+	if px < end {
+		return a.keys[k]
+	}
+	if a.size == 0 {
+		return a.keys[k] + 1
+	}
+	return a.keys[k] + 1 + (px-end)/a.size
+}
@@ -0,0 +1,156 @@
+package grid
+
+// Merge a range of cells into a single cell that spans the range. The
+// value of the top left cell is kept, the values of the other cells
+// are cleared. Merges overlapping the range are removed first. The
+// merge and the cleared values are a single undoable step.
+func (sh *Sheet) MergeCells(r Range) {
+	r = NewRange(r.From, r.To)
+	sh.commitEdit()
+	sh.history.begin()
+	defer sh.history.end()
+	old := sh.GetMerges()
+	sh.removeMerges(r)
+	if r.From != r.To {
+		values := map[Address]string{}
+		for _, a := range sh.index.query(r) {
+			if a != r.From && sh.filled(a) {
+				values[a] = ""
+			}
+		}
+		if len(values) > 0 {
+			sh.setValues(values)
+		}
+		sh.merges = append(sh.merges, r)
+	}
+	sh.recordMerges(old)
+}
+
+// Split the merged cells overlapping a range back into single cells.
+func (sh *Sheet) UnmergeCells(r Range) {
+	old := sh.GetMerges()
+	sh.removeMerges(NewRange(r.From, r.To))
+	sh.recordMerges(old)
+}
+
+// A change of the merged cells.
+type mergeEdit struct {
+	old, new []Range
+}
+
+func (e mergeEdit) undo(sh *Sheet) {
+	sh.setMerges(e.old)
+}
+
+func (e mergeEdit) redo(sh *Sheet) {
+	sh.setMerges(e.new)
+}
+
+// Record the change of the merges from old, if they changed.
+func (sh *Sheet) recordMerges(old []Range) {
+	if len(old) == len(sh.merges) {
+		same := true
+		for i := range old {
+			same = same && old[i] == sh.merges[i]
+		}
+		if same {
+			return
+		}
+	}
+	sh.history.add(mergeEdit{old, sh.GetMerges()})
+	sh.fitSelection()
+}
+
+// Replace the merges with a copy of merges.
+func (sh *Sheet) setMerges(merges []Range) {
+	sh.merges = append([]Range{}, merges...)
+	sh.fitSelection()
+}
+
+// Grow the selection to the merges it overlaps since it can't include
+// part of a merge.
+func (sh *Sheet) fitSelection() {
+	s := &sh.selection
+	for i, sr := range s.ranges {
+		s.ranges[i] = sh.expandMerges(sr)
+	}
+	s.anchor = sh.cellRange(s.anchor).From
+	s.active = sh.cellRange(s.active).From
+}
+
+func (sh *Sheet) removeMerges(r Range) {
+	merges := sh.merges[:0]
+	for _, m := range sh.merges {
+		if !overlaps(m, r) {
+			merges = append(merges, m)
+		}
+	}
+	sh.merges = merges
+}
+
+// The merged ranges.
+func (sh *Sheet) GetMerges() []Range {
+	return append([]Range{}, sh.merges...)
+}
+
+// The merge containing a. Returns false if a is not merged.
+func (sh *Sheet) mergeAt(a Address) (Range, bool) {
+	for _, m := range sh.merges {
+		if m.Contains(a) {
+			return m, true
+		}
+	}
+	return Range{}, false
+}
+
+// The range a cell covers, the merge it is in or the cell itself.
+func (sh *Sheet) cellRange(a Address) Range {
+	if m, ok := sh.mergeAt(a); ok {
+		return m
+	}
+	return Range{a, a}
+}
+
+// Check if a is covered by a merge but is not the first cell of it.
+func (sh *Sheet) hidden(a Address) bool {
+	m, ok := sh.mergeAt(a)
+	return ok && m.From != a
+}
+
+// Grow a range until it contains every merge it overlaps.
+func (sh *Sheet) expandMerges(r Range) Range {
+	for grown := true; grown; {
+		grown = false
+		for _, m := range sh.merges {
+			if overlaps(m, r) && !(r.Contains(m.From) && r.Contains(m.To)) {
+				r = NewRange(
+					Address{minInt(r.From.Row, m.From.Row), minInt(r.From.Col, m.From.Col)},
+					Address{maxInt(r.To.Row, m.To.Row), maxInt(r.To.Col, m.To.Col)})
+				grown = true
+			}
+		}
+	}
+	return r
+}
+
+// The size in pixels of the area a cell covers.
+func (sh *Sheet) cellSize(a Address) (int, int) {
+	r := sh.cellRange(a)
+	w := sh.cols.offset(r.To.Col+1) - sh.cols.offset(r.From.Col)
+	h := sh.rows.offset(r.To.Row+1) - sh.rows.offset(r.From.Row)
+	return w, h
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,226 @@
+package grid
+
+// Move the active cell for a navigation key. Shift extends the
+// selection, except for Enter and Tab where it reverses the direction.
+// Ctrl moves to the edge of the data region. Returns false if the key
+// is not a navigation key.
+func (sh *Sheet) navigate(key string, shift, ctrl bool) bool {
+	if sh.selection.empty() {
+		sh.selection.set(Address{0, 0})
+	}
+	// Extending moves the corner of the range opposite the anchor.
+	from := sh.selection.active
+	if shift {
+		from = sh.selectionCorner()
+	}
+	to := from
+	switch key {
+	case "ArrowUp", "ArrowDown", "ArrowLeft", "ArrowRight":
+		dr, dc := arrowDirection(key)
+		from = sh.mergeEdge(from, dr, dc)
+		if ctrl {
+			to = sh.dataEdge(from, dr, dc)
+		} else {
+			to = Address{from.Row + dr, from.Col + dc}
+		}
+	case "Home":
+		to.Col = 0
+		if ctrl {
+			to.Row = 0
+		}
+	case "End":
+		to = sh.lastDataCell(from.Row, ctrl)
+	case "PageDown", "PageUp":
+		page := sh.panes()[0].h
+		if key == "PageUp" {
+			page = -page
+		}
+		to.Row = sh.rows.index(sh.rows.offset(from.Row) + page)
+		sh.setView(sh.x, sh.y+page)
+	case "Enter", "Tab":
+		from = sh.selection.active
+		d := 1
+		if shift {
+			d = -1
+		}
+		if key == "Tab" {
+			from = sh.mergeEdge(from, 0, d)
+			to = Address{from.Row, from.Col + d}
+		} else {
+			from = sh.mergeEdge(from, d, 0)
+			to = Address{from.Row + d, from.Col}
+		}
+		shift = false
+	default:
+		return false
+	}
+	if to.Row < 0 {
+		to.Row = 0
+	}
+	if to.Col < 0 {
+		to.Col = 0
+	}
+	if shift {
+		sh.selection.extend(to)
+	} else {
+		sh.selection.set(to)
+	}
+	sh.scrollTo(to)
+	return true
+}
+
+func arrowDirection(key string) (int, int) {
+	switch key {
+	case "ArrowUp":
+		return -1, 0
+	case "ArrowDown":
+		return 1, 0
+	case "ArrowLeft":
+		return 0, -1
+	}
+	return 0, 1
+}
+
+// The corner of the current range opposite the anchor.
+func (sh *Sheet) selectionCorner() Address {
+	r, _ := sh.selection.current()
+	a := sh.selection.anchor
+	corner := r.To
+	if a.Row != r.From.Row {
+		corner.Row = r.From.Row
+	}
+	if a.Col != r.From.Col {
+		corner.Col = r.From.Col
+	}
+	return corner
+}
+
+// The cell of the merge containing a on the side moving in the
+// direction dr, dc, so moving steps over the whole merge.
+func (sh *Sheet) mergeEdge(a Address, dr, dc int) Address {
+	m := sh.cellRange(a)
+	if dr > 0 {
+		a.Row = m.To.Row
+	}
+	if dc > 0 {
+		a.Col = m.To.Col
+	}
+	return a
+}
+
+// Check if a cell has a value.
+func (sh *Sheet) filled(a Address) bool {
+	c, ok := sh.data[a]
+	return ok && c.value != ""
+}
+
+// Find the cell a Ctrl+Arrow moves to from a in the direction dr, dc.
+// Inside a block of values it is the last value of the block, otherwise
+// the next value. Without a next value it is the first row or column
+// moving up or left and a itself moving down or right since the grid
+// has no last row or column.
+func (sh *Sheet) dataEdge(a Address, dr, dc int) Address {
+	next := Address{a.Row + dr, a.Col + dc}
+	if next.Row < 0 || next.Col < 0 {
+		return a
+	}
+	if sh.filled(a) && sh.filled(next) {
+		for {
+			n := Address{next.Row + dr, next.Col + dc}
+			if n.Row < 0 || n.Col < 0 || !sh.filled(n) {
+				return next
+			}
+			next = n
+		}
+	}
+	// The nearest value beyond a on the same row or column.
+	found := false
+	nearest := a
+	dist := func(b Address) int {
+		return (b.Row-a.Row)*dr + (b.Col-a.Col)*dc
+	}
+	for b, c := range sh.data {
+		if c.value == "" || dr == 0 && b.Row != a.Row || dc == 0 && b.Col != a.Col {
+			continue
+		}
+		if d := dist(b); d > 0 && (!found || d < dist(nearest)) {
+			nearest = b
+			found = true
+		}
+	}
+	if found {
+		return nearest
+	}
+	if dr < 0 {
+		return Address{0, a.Col}
+	}
+	if dc < 0 {
+		return Address{a.Row, 0}
+	}
+	return a
+}
+
+// The last cell with a value in a row, or the bottom right corner of
+// all the values if all is true.
+func (sh *Sheet) lastDataCell(row int, all bool) Address {
+	last := Address{row, 0}
+	if all {
+		last.Row = 0
+	}
+	for a, c := range sh.data {
+		if c.value == "" || !all && a.Row != row {
+			continue
+		}
+		if a.Col > last.Col {
+			last.Col = a.Col
+		}
+		if all && a.Row > last.Row {
+			last.Row = a.Row
+		}
+	}
+	return last
+}
+
+// Scroll the view-port so a cell is visible. Frozen rows and columns
+// are always visible.
+func (sh *Sheet) scrollTo(a Address) {
+	fw, fh := sh.frozenSize()
+	p := sh.panes()[0]
+	x, y := sh.x, sh.y
+	if a.Col >= sh.frozenCols {
+		left := sh.cols.offset(a.Col)
+		right := left + sh.cols.sizeOf(a.Col)
+		if right > p.gx+p.w {
+			x = right - p.w - fw
+		}
+		if left < fw+x {
+			x = left - fw
+		}
+	}
+	if a.Row >= sh.frozenRows {
+		top := sh.rows.offset(a.Row)
+		bottom := top + sh.rows.sizeOf(a.Row)
+		if bottom > p.gy+p.h {
+			y = bottom - p.h - fh
+		}
+		if top < fh+y {
+			y = top - fh
+		}
+	}
+	sh.setView(x, y)
+}
+
+// Move the view-port to the grid coordinates x and y.
+func (sh *Sheet) setView(x, y int) {
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	if x == sh.x && y == sh.y {
+		return
+	}
+	sh.x = x
+	sh.y = y
+}
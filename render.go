@@ -0,0 +1,145 @@
+package grid
+
+import (
+	"sort"
+)
+
+// A Renderer draws the grid. The methods follow the canvas 2D context:
+// the colors, line style and font are state that Save and Restore push
+// and pop together with the clip region. Colors are CSS colors, fonts
+// are CSS font strings and coordinates are view-port pixels.
+type Renderer interface {
+	Save()
+	Restore()
+	// Intersect the clip region with a rectangle.
+	Clip(x, y, w, h int)
+	SetFont(font string)
+	SetFillStyle(color string)
+	FillStyle() string
+	SetStrokeStyle(color string)
+	SetLineWidth(width float64)
+	// The lengths of the dashes and gaps of lines, solid if empty.
+	SetLineDash(dash []float64)
+	SetShadow(color string, blur float64)
+	FillRect(x, y, w, h int)
+	StrokeRect(x, y, w, h int)
+	BeginPath()
+	MoveTo(x, y float64)
+	LineTo(x, y float64)
+	Stroke()
+	// Draw a line of text with its top at y. The text starts at x,
+	// is centered on x or ends at x depending on the alignment.
+	FillText(text string, x, y int, align HAlign)
+	MeasureText(text string) float64
+	// The height of a line of text in the current font.
+	LineHeight() int
+}
+
+// A cellStyler can change the styles of the cells while they are drawn.
+// The grid's Container is the cellStyler of its Sheet.
+type cellStyler interface {
+	SetCellStyles(row, col int)
+	SetCellFontStyles(row, col int)
+}
+
+// Draw the view-port of the Sheet: the cells, borders, selection,
+// headers and freeze lines.
+func (sh *Sheet) Render(r Renderer) {
+	sh.render(r, sh.drawPaneBackground)
+}
+
+// Draw the view-port. The background of each pane, including its grid
+// lines, is drawn by background with the index of the pane.
+func (sh *Sheet) render(r Renderer, background func(r Renderer, i int, p pane)) {
+	for i, p := range sh.panes() {
+		if p.w <= 0 || p.h <= 0 {
+			continue
+		}
+		// Only the cells inside the pane are drawn. Empty cells with a
+		// background are drawn first. Merged cells are drawn by the
+		// first cell of the merge, even when it is outside the pane.
+		rg := sh.paneRange(p)
+		cells := []*cell{}
+		for _, m := range sh.merges {
+			if c := sh.cellAt(m.From); overlaps(m, rg) && c != sh.editCell {
+				cells = append(cells, c)
+			}
+		}
+		for _, a := range sh.styledCells(rg) {
+			if _, ok := sh.mergeAt(a); !ok {
+				cells = append(cells, &cell{row: a.Row, col: a.Col, sheet: sh})
+			}
+		}
+		// The data cells are drawn in row and column order so the
+		// same Sheet is always drawn the same way.
+		addresses := sh.index.query(rg)
+		sort.Slice(addresses, func(i, j int) bool {
+			a, b := addresses[i], addresses[j]
+			return a.Row < b.Row || a.Row == b.Row && a.Col < b.Col
+		})
+		for _, a := range addresses {
+			// Edit cell may or may not be added to data cells yet.
+			// Don't double draw.
+			if _, ok := sh.mergeAt(a); ok {
+				continue
+			}
+			if c := sh.data[a]; c != sh.editCell {
+				cells = append(cells, c)
+			}
+		}
+		// Draw the edit cell last.
+		if c := sh.editCell; c != nil && overlaps(sh.cellRange(Address{c.row, c.col}), rg) {
+			cells = append(cells, c)
+		}
+		r.Save()
+		r.Clip(p.vx, p.vy, p.w, p.h)
+		background(r, i, p)
+
+		// Draw the data cells.
+		r.Save()
+		for _, c := range cells {
+			sh.drawCell(r, c)
+		}
+		r.Restore()
+
+		sh.drawCellBorders(r, p, rg)
+		sh.drawSelection(r, p, rg)
+		r.Restore()
+	}
+	sh.drawFreezeLines(r)
+	sh.drawHeaders(r)
+}
+
+// Fill a pane white and draw its grid lines.
+func (sh *Sheet) drawPaneBackground(r Renderer, i int, p pane) {
+	r.SetFillStyle("white")
+	r.FillRect(p.vx, p.vy, p.w, p.h)
+	sh.strokeGridLines(r, p.gx, p.gy, p.w, p.h, p.vx, p.vy)
+}
+
+// Stroke the grid lines of the area of the grid at gx, gy with the
+// size w, h at the view coordinates vx, vy.
+func (sh *Sheet) strokeGridLines(r Renderer, gx, gy, w, h, vx, vy int) {
+	r.Save()
+	r.SetStrokeStyle("black")
+	r.SetLineWidth(0.25)
+	r.BeginPath()
+	for i := sh.cols.index(gx); ; i++ {
+		x := sh.cols.offset(i) - gx
+		if x > w {
+			break
+		}
+		r.MoveTo(float64(vx+x), float64(vy))
+		r.LineTo(float64(vx+x), float64(vy+h))
+	}
+	for i := sh.rows.index(gy); ; i++ {
+		y := sh.rows.offset(i) - gy
+		if y > h {
+			break
+		}
+		r.MoveTo(float64(vx), float64(vy+y))
+		r.LineTo(float64(vx+w), float64(vy+y))
+	}
+	r.Stroke()
+	r.Restore()
+}
@@ -0,0 +1,147 @@
+package grid
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden images in testdata")
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		s    string
+		want color.NRGBA
+		ok   bool
+	}{
+		{"white", color.NRGBA{255, 255, 255, 255}, true},
+		{"LightGray", color.NRGBA{211, 211, 211, 255}, true},
+		{"#f3f3f3", color.NRGBA{243, 243, 243, 255}, true},
+		{"#f00", color.NRGBA{255, 0, 0, 255}, true},
+		{"#00ff0080", color.NRGBA{0, 255, 0, 128}, true},
+		{"rgb(1, 2, 3)", color.NRGBA{1, 2, 3, 255}, true},
+		{"rgba(0, 120, 215, 0.3)", color.NRGBA{0, 120, 215, 77}, true},
+		{"rgb(100%, 0%, 50%)", color.NRGBA{255, 0, 128, 255}, true},
+		{"#12345", color.NRGBA{}, false},
+		{"hsl(0, 0%, 0%)", color.NRGBA{}, false},
+		{"nocolor", color.NRGBA{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseColor(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseColor(%q) = %v, %v, want %v, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestImageRenderer(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	r := NewImageRenderer(img)
+	r.SetFillStyle("red")
+	r.Save()
+	r.Clip(2, 2, 4, 4)
+	r.FillRect(0, 0, 10, 10)
+	r.Restore()
+	r.SetStrokeStyle("blue")
+	r.SetLineWidth(1)
+	r.BeginPath()
+	r.MoveTo(0, 8.5)
+	r.LineTo(10, 8.5)
+	r.Stroke()
+
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	for _, tt := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{1, 1, color.RGBA{}},
+		{2, 2, red},
+		{5, 5, red},
+		{6, 6, color.RGBA{}},
+		{0, 8, blue},
+		{9, 8, blue},
+		{0, 9, color.RGBA{}},
+	} {
+		if got := img.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("pixel %d, %d = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+// Render a Sheet with most of the drawn features and compare it to a
+// golden image. Run the test with -update to write the image again
+// after an intended change to the drawing.
+func TestRender(t *testing.T) {
+	sh := NewSheet(420, 200, 80, 20)
+	sh.headerWidth, sh.headerHeight = rowHeaderWidth, 20
+	sh.addData(0, 0, "Name")
+	sh.addData(0, 1, "Amount")
+	sh.addData(1, 0, "Apples")
+	sh.addData(1, 1, "12.5")
+	sh.addData(2, 0, "Pears")
+	sh.addData(2, 1, "=B2*2")
+	sh.addData(3, 0, "This text overflows to the right")
+	sh.addData(5, 2, "Wrapped text in a cell")
+	sh.SetRangeStyle(r1("A1:B1"), CellStyle{Background: "#eeeeee", FontWeight: "bold"})
+	sh.SetCellAlignment(5, 2, Alignment{Wrap: true, Vertical: AlignTop})
+	sh.SetRowHeight(5, 40)
+	sh.SetCellFormat(1, 1, "0.00")
+	sh.OutlineRange(r1("A1:B3"), Border{Color: "navy", Width: 2})
+	sh.SetRangeBorders(r1("D2:D3"), Border{Color: "red", Width: 1, Dash: "dashed"})
+	sh.addData(6, 3, "Merged")
+	sh.MergeCells(r1("D7:E8"))
+	sh.SetCellStyle(6, 3, CellStyle{Background: "lightyellow", Alignment: Alignment{Horizontal: AlignCenter, Vertical: AlignMiddle}})
+	sh.FreezePanes(1, 0)
+	sh.selection.setRange(r1("B2:B3"))
+
+	img := image.NewRGBA(image.Rect(0, 0, sh.width, sh.height))
+	sh.Render(NewImageRenderer(img))
+
+	goldenImage(t, "render.png", img)
+}
+
+// Compare the pixels of an image to a golden image in testdata, or write
+// the golden image with -update.
+func goldenImage(t *testing.T, name string, img image.Image) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Bounds() != img.Bounds() {
+		t.Fatalf("image size %v, want %v", img.Bounds(), want.Bounds())
+	}
+	diff := 0
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			if color.RGBAModel.Convert(want.At(x, y)) != color.RGBAModel.Convert(img.At(x, y)) {
+				diff++
+			}
+		}
+	}
+	if diff > 0 {
+		t.Errorf("%d pixels differ from %s", diff, path)
+	}
+}
@@ -0,0 +1,72 @@
+package grid
+
+// The distance in pixels from a column or row border that starts a
+// resize.
+const resizeMargin = 3
+
+// The smallest size a column or row can be resized to.
+const minResizeSize = 5
+
+// An interactive resize of a column or row.
+type resize struct {
+	column bool // resizing a column, otherwise a row
+	index  int
+	start  int // page coordinate where the drag started
+	size   int // size when the drag started
+}
+
+// Find the index whose trailing border is near the offset px.
+func nearBorder(a *axis, px int) (int, bool) {
+	i := a.index(px)
+	start := a.offset(i)
+	if px-start <= resizeMargin && i > 0 {
+		return i - 1, true
+	}
+	if start+a.sizeOf(i)-px <= resizeMargin {
+		return i, true
+	}
+	return 0, false
+}
+
+// Find the column or row border to resize at the view-port coordinates.
+// The borders are dragged in the headers, or between the cells when
+// the headers are hidden. Returns false if the coordinates are not near
+// a border.
+func (sh *Sheet) resizeBorderAt(vx, vy int) (column bool, index int, ok bool) {
+	gx, gy := sh.viewToGrid(vx, vy)
+	inCols := vy < sh.headerHeight && vx >= sh.headerWidth
+	inRows := vx < sh.headerWidth && vy >= sh.headerHeight
+	if inCols || sh.headerHeight == 0 && !inRows {
+		if i, ok := nearBorder(&sh.cols, gx); ok {
+			return true, i, true
+		}
+	}
+	if inRows || sh.headerWidth == 0 && !inCols {
+		if i, ok := nearBorder(&sh.rows, gy); ok {
+			return false, i, true
+		}
+	}
+	return false, 0, false
+}
+
+// The first and last scrolling rows or columns in the view-port. The
+// frozen rows and columns are always visible.
+func (sh *Sheet) visibleRows() (int, int) {
+	_, fh := sh.frozenSize()
+	return sh.rows.index(fh + sh.y), sh.rows.index(sh.y + sh.height)
+}
+
+func (sh *Sheet) visibleCols() (int, int) {
+	fw, _ := sh.frozenSize()
+	return sh.cols.index(fw + sh.x), sh.cols.index(sh.x + sh.width)
+}
+
+// Measure the displayed text of a cell with its font applied to the
+// Renderer. The font is left set on the Renderer.
+func (sh *Sheet) measureCell(r Renderer, c *cell) float64 {
+	r.SetFont(sh.GetCellStyle(c.row, c.col).font())
+	if sh.styler != nil {
+		sh.styler.SetCellFontStyles(c.row, c.col)
+	}
+	return r.MeasureText(c.text())
+}
@@ -0,0 +1,133 @@
+package grid
+
+// Find the column or row border to resize at the page coordinates.
+// Returns false if the coordinates are not near a border.
+func (g *grid) borderAt(x, y int) (resize, bool) {
+	column, i, ok := g.resizeBorderAt(g.pageToView(x, y))
+	switch {
+	case !ok:
+		return resize{}, false
+	case column:
+		return resize{true, i, x, g.cols.sizeOf(i)}, true
+	}
+	return resize{false, i, y, g.rows.sizeOf(i)}, true
+}
+
+// Continue an interactive resize to the page coordinates.
+func (g *grid) resizeTo(r *resize, x, y int) {
+	pos := y
+	if r.column {
+		pos = x
+	}
+	size := r.size + pos - r.start
+	if size < minResizeSize {
+		size = minResizeSize
+	}
+	if r.column {
+		g.cols.set(r.index, size)
+	} else {
+		g.rows.set(r.index, size)
+	}
+	g.drawBackGround()
+	g.draw()
+}
+
+// Show a resize cursor when the mouse is over a header border.
+func (g *grid) updateCursor(x, y int) {
+	cursor := ""
+	if r, ok := g.borderAt(x, y); ok {
+		cursor = "row-resize"
+		if r.column {
+			cursor = "col-resize"
+		}
+	}
+	g.vcnv.Get("style").Set("cursor", cursor)
+}
+
+// Resize a column to fit the widest text of its visible cells.
+func (g *grid) autoFitColumn(col int) {
+	width := 0
+	first, last := g.visibleRows()
+	g.renderer.Save()
+	for row := 0; row <= last; row++ {
+		if row == g.frozenRows && first > row {
+			row = first
+		}
+		if c, ok := g.data[Address{row, col}]; ok {
+			if w := int(g.measureCell(g.renderer, c)); w > width {
+				width = w
+			}
+		}
+	}
+	g.renderer.Restore()
+	if width == 0 {
+		width = g.cols.size
+	} else {
+		width += 6
+	}
+	g.cols.set(col, width)
+	g.drawBackGround()
+	g.draw()
+	g.resized(true, col)
+}
+
+// Resize a row to fit the tallest text of its visible cells, including
+// the lines of wrapped text.
+func (g *grid) autoFitRow(row int) {
+	height := 0
+	first, last := g.visibleCols()
+	r := g.renderer
+	r.Save()
+	for col := 0; col <= last; col++ {
+		if col == g.frozenCols && first > col {
+			col = first
+		}
+		if c, ok := g.data[Address{row, col}]; ok {
+			g.measureCell(r, c)
+			h := r.LineHeight()
+			// Wrapped text is as tall as its lines.
+			if g.alignment(row, col).Wrap {
+				w := float64(g.cols.sizeOf(col) - 2*g.GetCellStyle(row, col).Padding)
+				h *= len(wrapText(c.text(), w, r.MeasureText))
+			}
+			if h > height {
+				height = h
+			}
+		}
+	}
+	r.Restore()
+	if height == 0 {
+		height = g.rows.size
+	} else {
+		height += 6
+	}
+	g.rows.set(row, height)
+	g.drawBackGround()
+	g.draw()
+	g.resized(false, row)
+}
+
+// A Container that implements ResizeListener is told of the columns
+// and rows resized by the user.
+type ResizeListener interface {
+	ColumnResized(col, width int)
+	RowResized(row, height int)
+}
+
+// Notify the container and any JavaScript listeners on the grid element
+// that a column or row was resized by the user.
+func (g *grid) resized(column bool, index int) {
+	if column {
+		width := g.cols.sizeOf(index)
+		if l, ok := g.container.(ResizeListener); ok {
+			l.ColumnResized(index, width)
+		}
+		dispatchEvent(g.main, "columnresize", map[string]interface{}{"col": index, "width": width})
+		return
+	}
+	height := g.rows.sizeOf(index)
+	if l, ok := g.container.(ResizeListener); ok {
+		l.RowResized(index, height)
+	}
+	dispatchEvent(g.main, "rowresize", map[string]interface{}{"row": index, "height": height})
+}
@@ -0,0 +1,430 @@
+package grid
+
+import (
+	"strconv"
+)
+
+// A structural change to the rows or columns of the grid: count rows
+// or columns inserted before index, or removed starting at index when
+// count is negative.
+type axisChange struct {
+	rows  bool // rows, otherwise columns
+	index int
+	count int
+}
+
+// The new position of row or column i. Returns false if i was removed.
+func (c axisChange) pos(i int) (int, bool) {
+	switch {
+	case i < c.index:
+		return i, true
+	case c.count >= 0:
+		return i + c.count, true
+	case i < c.index-c.count:
+		return 0, false
+	}
+	return i + c.count, true
+}
+
+// The new Address of a cell. Returns false if the cell was removed.
+func (c axisChange) address(a Address) (Address, bool) {
+	i := a.Col
+	if c.rows {
+		i = a.Row
+	}
+	i, ok := c.pos(i)
+	if c.rows {
+		a.Row = i
+	} else {
+		a.Col = i
+	}
+	return a, ok
+}
+
+// The new Range of the cells of r. Rows or columns inserted inside the
+// range grow it and removed ones shrink it. Returns false if every row
+// or column of the range was removed.
+func (c axisChange) rng(r Range) (Range, bool) {
+	from, to := r.From.Col, r.To.Col
+	if c.rows {
+		from, to = r.From.Row, r.To.Row
+	}
+	if c.count < 0 {
+		// The removed rows or columns at the ends of the range are
+		// cut off.
+		end := c.index - c.count
+		if from >= c.index && from < end {
+			from = end
+		}
+		if to >= c.index && to < end {
+			to = c.index - 1
+		}
+		if from > to {
+			return r, false
+		}
+	}
+	from, _ = c.pos(from)
+	to, _ = c.pos(to)
+	if c.rows {
+		r.From.Row, r.To.Row = from, to
+	} else {
+		r.From.Col, r.To.Col = from, to
+	}
+	return r, true
+}
+
+// The new number of frozen rows or columns when n were frozen.
+func (c axisChange) frozen(n int) int {
+	switch {
+	case c.index >= n:
+		return n
+	case c.count >= 0:
+		return n + c.count
+	}
+	end := c.index - c.count
+	if end > n {
+		end = n
+	}
+	return n - (end - c.index)
+}
+
+// Move every per row or per column setting of a map.
+func (c axisChange) keys(m map[int]string) map[int]string {
+	moved := map[int]string{}
+	for i, v := range m {
+		if i, ok := c.pos(i); ok {
+			moved[i] = v
+		}
+	}
+	return moved
+}
+
+// Insert count rows before row. The cells at and below row move down.
+func (sh *Sheet) AddRow(row, count int) {
+	if count > 0 {
+		sh.changeAxis(axisChange{true, row, count})
+	}
+}
+
+// Insert count columns before col. The cells at and right of col move
+// right.
+func (sh *Sheet) AddColumn(col, count int) {
+	if count > 0 {
+		sh.changeAxis(axisChange{false, col, count})
+	}
+}
+
+// Remove count rows starting at row. The cells below move up.
+func (sh *Sheet) DeleteRow(row, count int) {
+	if count > 0 {
+		sh.changeAxis(axisChange{true, row, -count})
+	}
+}
+
+// Remove count columns starting at col. The cells to the right move
+// left.
+func (sh *Sheet) DeleteColumn(col, count int) {
+	if count > 0 {
+		sh.changeAxis(axisChange{false, col, -count})
+	}
+}
+
+func (sh *Sheet) changeAxis(c axisChange) {
+	if c.count == 0 || c.index < 0 {
+		return
+	}
+	sh.commitEdit()
+	sh.moveCells(c)
+}
+
+// The settings of a Sheet that a row or column change moves, besides
+// the cell values.
+type sheetLayout struct {
+	selection              selection
+	styles                 styles
+	formats                formats
+	merges                 []Range
+	cols, rows             axis
+	colHeaders, rowHeaders map[int]string
+	frozenRows, frozenCols int
+}
+
+// A copy of the layout of the Sheet.
+func (sh *Sheet) layout() sheetLayout {
+	return sheetLayout{sh.selection, sh.styles, sh.formats, sh.merges, sh.cols, sh.rows,
+		sh.colHeaders, sh.rowHeaders, sh.frozenRows, sh.frozenCols}.copy()
+}
+
+// Replace the layout of the Sheet with a copy of l.
+func (sh *Sheet) setLayout(l sheetLayout) {
+	l = l.copy()
+	sh.selection, sh.styles, sh.formats, sh.merges = l.selection, l.styles, l.formats, l.merges
+	sh.cols, sh.rows = l.cols, l.rows
+	sh.colHeaders, sh.rowHeaders = l.colHeaders, l.rowHeaders
+	sh.frozenRows, sh.frozenCols = l.frozenRows, l.frozenCols
+}
+
+// A copy that shares no maps or slices with l.
+func (l sheetLayout) copy() sheetLayout {
+	l.selection.ranges = append([]Range{}, l.selection.ranges...)
+	l.styles = l.styles.copy()
+	l.formats = l.formats.copy()
+	l.merges = append([]Range{}, l.merges...)
+	l.cols, l.rows = l.cols.copy(), l.rows.copy()
+	l.colHeaders, l.rowHeaders = copyLabels(l.colHeaders), copyLabels(l.rowHeaders)
+	return l
+}
+
+// A row or column change of the layout. It is undone by restoring the
+// layout from before the change since the settings of removed rows or
+// columns can't be moved back.
+type layoutEdit struct {
+	old, new sheetLayout
+}
+
+func (e layoutEdit) undo(sh *Sheet) {
+	sh.setLayout(e.old)
+}
+
+func (e layoutEdit) redo(sh *Sheet) {
+	sh.setLayout(e.new)
+}
+
+func copyLabels(m map[int]string) map[int]string {
+	c := map[int]string{}
+	for i, s := range m {
+		c[i] = s
+	}
+	return c
+}
+
+// Apply a row or column change to the data, formulas, selection,
+// styles, formats, merges, sizes, header labels and frozen panes of the
+// grid as a single undoable step.
+func (sh *Sheet) moveCells(c axisChange) {
+	sh.history.begin()
+	defer sh.history.end()
+	old := sh.layout()
+	before := sh.snapshot()
+	data := map[Address]*cell{}
+	for a, cl := range sh.data {
+		if isFormula(cl.value) {
+			cl.value = "=" + moveRefs(cl.value[1:], c)
+		}
+		if a, ok := c.address(a); ok {
+			cl.row, cl.col = a.Row, a.Col
+			data[a] = cl
+		}
+	}
+	sh.data = data
+	sh.reindex()
+	sh.recalcAll()
+	sh.recordSnapshot(before)
+
+	merges := sh.merges[:0]
+	for _, m := range sh.merges {
+		if m, ok := c.rng(m); ok && m.From != m.To {
+			merges = append(merges, m)
+		}
+	}
+	sh.merges = merges
+	sh.selection.move(c)
+	sh.styles.move(c)
+	sh.formats.move(c)
+	if c.rows {
+		sh.rows.move(c)
+		sh.rowHeaders = c.keys(sh.rowHeaders)
+		sh.frozenRows = c.frozen(sh.frozenRows)
+	} else {
+		sh.cols.move(c)
+		sh.colHeaders = c.keys(sh.colHeaders)
+		sh.frozenCols = c.frozen(sh.frozenCols)
+	}
+	sh.history.add(layoutEdit{old, sh.layout()})
+}
+
+// Move the selected ranges. Ranges that were removed are dropped, an
+// active cell that was removed moves to the first cell after the
+// removed rows or columns.
+func (s *selection) move(c axisChange) {
+	ranges := s.ranges[:0]
+	for _, r := range s.ranges {
+		if r, ok := c.rng(r); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	s.ranges = ranges
+	point := func(a Address) Address {
+		if b, ok := c.address(a); ok {
+			return b
+		}
+		if c.rows {
+			a.Row = c.index
+		} else {
+			a.Col = c.index
+		}
+		return a
+	}
+	s.anchor = point(s.anchor)
+	s.active = point(s.active)
+	if len(s.ranges) == 0 {
+		s.set(s.active)
+	}
+}
+
+func (st *styles) move(c axisChange) {
+	cells := map[Address]CellStyle{}
+	for a, s := range st.cells {
+		if a, ok := c.address(a); ok {
+			cells[a] = s
+		}
+	}
+	st.cells = cells
+	ranges := st.ranges[:0]
+	for _, rs := range st.ranges {
+		if r, ok := c.rng(rs.r); ok {
+			ranges = append(ranges, rangeStyle{r, rs.style})
+		}
+	}
+	st.ranges = ranges
+	lines := &st.columns
+	if c.rows {
+		lines = &st.rows
+	}
+	moved := map[int]CellStyle{}
+	for i, s := range *lines {
+		if i, ok := c.pos(i); ok {
+			moved[i] = s
+		}
+	}
+	*lines = moved
+}
+
+func (f *formats) move(c axisChange) {
+	cells := map[Address]string{}
+	for a, format := range f.cells {
+		if a, ok := c.address(a); ok {
+			cells[a] = format
+		}
+	}
+	f.cells = cells
+	ranges := f.ranges[:0]
+	for _, rf := range f.ranges {
+		if r, ok := c.rng(rf.r); ok {
+			ranges = append(ranges, rangeFormat{r, rf.format})
+		}
+	}
+	f.ranges = ranges
+	if !c.rows {
+		f.columns = c.keys(f.columns)
+	}
+}
+
+// Move the custom sizes of the rows or columns.
+func (a *axis) move(c axisChange) {
+	sizes := map[int]int{}
+	for i, s := range a.sizes {
+		if i, ok := c.pos(i); ok {
+			sizes[i] = s
+		}
+	}
+	a.sizes = sizes
+	a.update()
+}
+
+// Rewrite the cell references of formula source for a row or column
+// change. A reference to a removed cell, or a range whose cells were
+// all removed, becomes #REF!. Text in quotes and function names are
+// kept as they are.
+func moveRefs(src string, c axisChange) string {
+	out := []byte{}
+	for i := 0; i < len(src); {
+		ch := src[i]
+		switch {
+		case ch == '"':
+			j := i + 1
+			for j < len(src) {
+				if src[j] == '"' {
+					if j+1 < len(src) && src[j+1] == '"' {
+						j += 2
+						continue
+					}
+					j++
+					break
+				}
+				j++
+			}
+			out = append(out, src[i:j]...)
+			i = j
+		case ch >= '0' && ch <= '9' || ch == '.':
+			// Numbers, including exponents such as 1E5.
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.' || isLetter(src[j])) {
+				j++
+			}
+			out = append(out, src[i:j]...)
+			i = j
+		case ch == '$' || ch == '_' || isLetter(ch):
+			j := identEnd(src, i)
+			a, ok := ParseAddress(src[i:j])
+			if !ok || j < len(src) && src[j] == '(' {
+				out = append(out, src[i:j]...)
+				i = j
+				continue
+			}
+			r := Range{a, a}
+			end := j
+			if j+1 < len(src) && src[j] == ':' {
+				k := identEnd(src, j+1)
+				if b, ok := ParseAddress(src[j+1 : k]); ok {
+					r = NewRange(a, b)
+					end = k
+				}
+			}
+			if end == j {
+				if a, ok := c.address(a); ok {
+					out = append(out, refText(src[i:j], a)...)
+				} else {
+					out = append(out, errRef...)
+				}
+			} else if r, ok := c.rng(r); ok {
+				out = append(out, refText(src[i:j], r.From)...)
+				out = append(out, ':')
+				out = append(out, refText(src[j+1:end], r.To)...)
+			} else {
+				out = append(out, errRef...)
+			}
+			i = end
+		default:
+			out = append(out, ch)
+			i++
+		}
+	}
+	return string(out)
+}
+
+// The end of the identifier in src starting at i.
+func identEnd(src string, i int) int {
+	for i < len(src) && (src[i] == '$' || src[i] == '_' ||
+		isLetter(src[i]) || src[i] >= '0' && src[i] <= '9') {
+		i++
+	}
+	return i
+}
+
+// The A1 text of a moved to a, keeping the $ markers of the original
+// reference text ref.
+func refText(ref string, a Address) string {
+	col := ColumnName(a.Col)
+	row := strconv.Itoa(a.Row + 1)
+	if ref[0] == '$' {
+		col = "$" + col
+	}
+	for i := 1; i < len(ref); i++ {
+		if ref[i] == '$' {
+			row = "$" + row
+			break
+		}
+	}
+	return col + row
+}
@@ -0,0 +1,272 @@
+package grid
+
+import (
+	"testing"
+)
+
+func a1(s string) Address {
+	a, _ := ParseAddress(s)
+	return a
+}
+
+func r1(s string) Range {
+	r, _ := ParseRange(s)
+	return r
+}
+
+func TestAxisChangePos(t *testing.T) {
+	tests := []struct {
+		c    axisChange
+		i    int
+		want int
+		ok   bool
+	}{
+		{axisChange{true, 3, 2}, 2, 2, true},
+		{axisChange{true, 3, 2}, 3, 5, true},
+		{axisChange{true, 3, -2}, 2, 2, true},
+		{axisChange{true, 3, -2}, 3, 0, false},
+		{axisChange{true, 3, -2}, 4, 0, false},
+		{axisChange{true, 3, -2}, 5, 3, true},
+	}
+	for _, tt := range tests {
+		got, ok := tt.c.pos(tt.i)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%v.pos(%d) = %d, %v, want %d, %v", tt.c, tt.i, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAxisChangeRange(t *testing.T) {
+	tests := []struct {
+		c    axisChange
+		r    string
+		want string
+		ok   bool
+	}{
+		{axisChange{true, 0, 1}, "A2:C4", "A3:C5", true},
+		{axisChange{true, 2, 2}, "A2:C4", "A2:C6", true},
+		{axisChange{true, 4, 1}, "A2:C4", "A2:C4", true},
+		{axisChange{false, 1, 1}, "A2:C4", "A2:D4", true},
+		{axisChange{true, 0, -1}, "A2:C4", "A1:C3", true},
+		{axisChange{true, 2, -1}, "A2:C4", "A2:C3", true},
+		{axisChange{true, 0, -2}, "A2:C4", "A1:C2", true},
+		{axisChange{true, 3, -5}, "A2:C4", "A2:C3", true},
+		{axisChange{true, 1, -3}, "A2:C4", "", false},
+		{axisChange{false, 1, -1}, "B1:B5", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.c.rng(r1(tt.r))
+		if ok != tt.ok || ok && got != r1(tt.want) {
+			t.Errorf("%v.rng(%s) = %s, %v, want %s, %v", tt.c, tt.r, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMoveRefs(t *testing.T) {
+	tests := []struct {
+		src  string
+		c    axisChange
+		want string
+	}{
+		{"A1+B2", axisChange{true, 1, 1}, "A1+B3"},
+		{"$B$2*B$2+$B2", axisChange{true, 0, 2}, "$B$4*B$4+$B4"},
+		{"SUM(A1:A5)", axisChange{true, 1, -2}, "SUM(A1:A3)"},
+		{"SUM(C1:E1)", axisChange{false, 1, 1}, "SUM(D1:F1)"},
+		{"A1+B2", axisChange{true, 1, -1}, "A1+#REF!"},
+		{"SUM(A2:B3)", axisChange{true, 0, -5}, "SUM(#REF!)"},
+		{`"A1"&A1`, axisChange{true, 0, 1}, `"A1"&A2`},
+		{"LOG10(A1)+1E5", axisChange{false, 0, 1}, "LOG10(B1)+1E5"},
+	}
+	for _, tt := range tests {
+		if got := moveRefs(tt.src, tt.c); got != tt.want {
+			t.Errorf("moveRefs(%q, %v) = %q, want %q", tt.src, tt.c, got, tt.want)
+		}
+	}
+}
+
+// Values must move once, including cells that were selected.
+func TestAddRow(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	for row, v := range []string{"1", "2", "3", "4"} {
+		sh.addData(row, 0, v)
+	}
+	sh.addData(4, 0, "=SUM(A1:A4)")
+	sh.selection.setRange(r1("A2:A3"))
+	sh.styles.setCell(a1("A3"), CellStyle{Color: "red"})
+	sh.styles.setRow(2, CellStyle{Background: "yellow"})
+	sh.merges = []Range{r1("B2:C3")}
+	sh.rows.set(3, 40)
+	sh.rowHeaders[1] = "two"
+
+	sh.moveCells(axisChange{true, 1, 2})
+
+	want := map[string]string{"A1": "1", "A4": "2", "A5": "3", "A6": "4", "A7": "=SUM(A1:A6)"}
+	if len(sh.data) != len(want) {
+		t.Errorf("%d cells, want %d", len(sh.data), len(want))
+	}
+	for s, v := range want {
+		c, ok := sh.data[a1(s)]
+		if !ok || c.value != v || (Address{c.row, c.col}) != a1(s) {
+			t.Errorf("cell %s = %+v, want %q", s, c, v)
+		}
+	}
+	if n, _ := sh.data[a1("A7")].val.Number(); n != 10 {
+		t.Errorf("A7 = %v, want 10", sh.data[a1("A7")].val)
+	}
+	if got := sh.GetSelection(); len(got) != 1 || got[0] != r1("A4:A5") {
+		t.Errorf("selection = %v, want A4:A5", got)
+	}
+	if sh.selection.active != a1("A4") {
+		t.Errorf("active = %s, want A4", sh.selection.active)
+	}
+	if s := sh.styles.get(a1("A5")); s.Color != "red" || s.Background != "yellow" {
+		t.Errorf("A5 style = %+v", s)
+	}
+	if sh.merges[0] != r1("B4:C5") {
+		t.Errorf("merge = %s, want B4:C5", sh.merges[0])
+	}
+	if sh.rows.sizeOf(5) != 40 || sh.rows.sizeOf(3) != 20 {
+		t.Errorf("row 5 height = %d, row 3 height = %d", sh.rows.sizeOf(5), sh.rows.sizeOf(3))
+	}
+	if sh.rowHeaders[3] != "two" {
+		t.Errorf("row headers = %v", sh.rowHeaders)
+	}
+
+	// The moved values are one undoable step.
+	if len(sh.history.undo) != 6 {
+		t.Fatalf("%d undo steps, want 6", len(sh.history.undo))
+	}
+	sh.history.undo[5].undo(sh)
+	if c, ok := sh.data[a1("A2")]; !ok || c.value != "2" {
+		t.Errorf("A2 after undo = %+v, want 2", c)
+	}
+}
+
+func TestDeleteColumn(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	sh.addData(0, 0, "1")
+	sh.addData(0, 1, "2")
+	sh.addData(0, 2, "3")
+	sh.addData(1, 0, "=A1+C1")
+	sh.addData(1, 3, "=B1")
+	sh.formats.setColumn(2, "0.00")
+	sh.selection.set(a1("B1"))
+	sh.merges = []Range{r1("B3:C3"), r1("C4:D4")}
+
+	sh.moveCells(axisChange{false, 1, -1})
+
+	want := map[string]string{"A1": "1", "B1": "3", "A2": "=A1+B1", "C2": "=#REF!"}
+	if len(sh.data) != len(want) {
+		t.Errorf("%d cells, want %d", len(sh.data), len(want))
+	}
+	for s, v := range want {
+		if c, ok := sh.data[a1(s)]; !ok || c.value != v {
+			t.Errorf("cell %s = %+v, want %q", s, c, v)
+		}
+	}
+	if n, _ := sh.data[a1("A2")].val.Number(); n != 4 {
+		t.Errorf("A2 = %v, want 4", sh.data[a1("A2")].val)
+	}
+	if v := sh.data[a1("C2")].val; v.Kind() != ErrorKind || v.Text() != errRef {
+		t.Errorf("C2 = %v, want %s", v, errRef)
+	}
+	if sh.formats.get(a1("B1")) != "0.00" {
+		t.Errorf("B1 format = %q, want 0.00", sh.formats.get(a1("B1")))
+	}
+	if got := sh.GetSelection(); len(got) != 1 || got[0] != r1("B1") {
+		t.Errorf("selection = %v, want B1", got)
+	}
+	// A merge cut down to one cell is no longer a merge.
+	if len(sh.merges) != 1 || sh.merges[0] != r1("B4:C4") {
+		t.Errorf("merges = %v, want [B4:C4]", sh.merges)
+	}
+}
+
+// Undoing a row or column change moves the values, styles, merges,
+// sizes and header labels back together, and redo moves them again.
+func TestAxisChangeUndo(t *testing.T) {
+	red := CellStyle{Color: "red"}
+	check := func(t *testing.T, sh *Sheet, row int) {
+		t.Helper()
+		a := Address{row, 0}
+		if c, ok := sh.data[a]; !ok || c.value != "x" {
+			t.Errorf("%s = %+v, want x", a, c)
+		}
+		if s := sh.styles.get(a); s.Color != "red" {
+			t.Errorf("%s style = %+v, want red", a, s)
+		}
+		if h := sh.rows.sizeOf(row); h != 50 {
+			t.Errorf("row %d height = %d, want 50", row+1, h)
+		}
+		if m := sh.GetMerges(); len(m) != 1 || m[0] != (Range{a, Address{row, 1}}) {
+			t.Errorf("merges = %v, want row %d", m, row+1)
+		}
+		if sh.rowHeaders[row] != "label" {
+			t.Errorf("row headers = %v, want row %d", sh.rowHeaders, row+1)
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(sh *Sheet)
+		row    int // the row of the cell after the change
+	}{
+		{"insert", func(sh *Sheet) { sh.AddRow(0, 1) }, 3},
+		{"delete", func(sh *Sheet) { sh.DeleteRow(0, 2) }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh := NewSheet(800, 600, 100, 20)
+			sh.addData(2, 0, "x")
+			sh.addData(0, 0, "removed")
+			sh.SetCellStyle(2, 0, red)
+			sh.SetCellStyle(0, 0, CellStyle{Color: "blue"})
+			sh.SetRowHeight(2, 50)
+			sh.MergeCells(r1("A3:B3"))
+			sh.SetRowHeader(2, "label")
+			sh.history = history{}
+
+			tt.change(sh)
+			check(t, sh, tt.row)
+			sh.Undo()
+			check(t, sh, 2)
+			if c, ok := sh.data[a1("A1")]; !ok || c.value != "removed" {
+				t.Errorf("A1 after undo = %+v, want removed", c)
+			}
+			if s := sh.styles.get(a1("A1")); s.Color != "blue" {
+				t.Errorf("A1 style after undo = %+v, want blue", s)
+			}
+			if sh.CanUndo() {
+				t.Errorf("the change was more than one step")
+			}
+			sh.Redo()
+			check(t, sh, tt.row)
+		})
+	}
+}
+
+func TestAxisChangeFrozen(t *testing.T) {
+	tests := []struct {
+		c    axisChange
+		want int
+	}{
+		{axisChange{true, 1, 2}, 5},
+		{axisChange{true, 3, 2}, 3},
+		{axisChange{true, 0, -2}, 1},
+		{axisChange{true, 2, -4}, 2},
+		{axisChange{true, 3, -1}, 3},
+	}
+	for _, tt := range tests {
+		if got := tt.c.frozen(3); got != tt.want {
+			t.Errorf("%v.frozen(3) = %d, want %d", tt.c, got, tt.want)
+		}
+	}
+
+	// A negative count doesn't change anything.
+	sh := NewSheet(800, 600, 100, 20)
+	sh.addData(2, 0, "x")
+	sh.AddRow(0, -2)
+	sh.DeleteRow(0, -2)
+	if _, ok := sh.data[a1("A3")]; !ok {
+		t.Errorf("a negative count moved the cells")
+	}
+}
@@ -0,0 +1,145 @@
+package grid
+
+// The selected cells of a grid. The selection is made of rectangular
+// ranges, the last one is the range being extended. The anchor is the
+// cell the last range was started from and the active cell is the cell
+// that input goes to.
+type selection struct {
+	ranges []Range
+	anchor Address
+	active Address
+	// Grows a range to the merged cells it overlaps so a merge is only
+	// ever selected as a whole.
+	expand func(Range) Range
+}
+
+func (s *selection) grow(r Range) Range {
+	if s.expand == nil {
+		return r
+	}
+	return s.expand(r)
+}
+
+// Select a single cell. A cell in a merge selects the merge with the
+// active cell at its top left.
+func (s *selection) set(a Address) {
+	r := s.grow(Range{a, a})
+	s.ranges = []Range{r}
+	s.anchor = r.From
+	s.active = r.From
+}
+
+// Select a range with the active cell at its top left.
+func (s *selection) setRange(r Range) {
+	s.set(r.From)
+	s.ranges[0] = s.grow(NewRange(r.From, r.To))
+}
+
+// Add a new single cell range to the selection.
+func (s *selection) add(a Address) {
+	r := s.grow(Range{a, a})
+	s.ranges = append(s.ranges, r)
+	s.anchor = r.From
+	s.active = r.From
+}
+
+// Extend the last range from the anchor to a. The active cell stays at
+// the anchor.
+func (s *selection) extend(a Address) {
+	if len(s.ranges) == 0 {
+		s.set(a)
+		return
+	}
+	s.ranges[len(s.ranges)-1] = s.grow(NewRange(s.anchor, a))
+}
+
+func (s *selection) clear() {
+	s.ranges = nil
+}
+
+func (s *selection) empty() bool {
+	return len(s.ranges) == 0
+}
+
+// The last range of the selection.
+func (s *selection) current() (Range, bool) {
+	if len(s.ranges) == 0 {
+		return Range{}, false
+	}
+	return s.ranges[len(s.ranges)-1], true
+}
+
+func (s *selection) contains(a Address) bool {
+	for _, r := range s.ranges {
+		if r.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if any range includes the column or row.
+func (s *selection) hasCol(col int) bool {
+	for _, r := range s.ranges {
+		if col >= r.From.Col && col <= r.To.Col {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *selection) hasRow(row int) bool {
+	for _, r := range s.ranges {
+		if row >= r.From.Row && row <= r.To.Row {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if two ranges share any cells.
+func overlaps(a, b Range) bool {
+	return a.From.Row <= b.To.Row && b.From.Row <= a.To.Row &&
+		a.From.Col <= b.To.Col && b.From.Col <= a.To.Col
+}
+
+// The selected ranges.
+func (sh *Sheet) GetSelection() []Range {
+	return append([]Range{}, sh.selection.ranges...)
+}
+
+// Draw an outline around each selected range and the active cell. The
+// outlines are drawn relative to the pane so ranges crossing into the
+// frozen rows or columns are clipped by the pane.
+func (sh *Sheet) drawSelection(r Renderer, p pane, visible Range) {
+	view := func(rg Range) (int, int, int, int) {
+		x := p.vx + sh.cols.offset(rg.From.Col) - p.gx
+		y := p.vy + sh.rows.offset(rg.From.Row) - p.gy
+		w := sh.cols.offset(rg.To.Col+1) - sh.cols.offset(rg.From.Col)
+		h := sh.rows.offset(rg.To.Row+1) - sh.rows.offset(rg.From.Row)
+		return x, y, w, h
+	}
+	shadowColor := "blue"
+	borderColor := "lightblue"
+	if sh.editCell != nil {
+		shadowColor = "green"
+		borderColor = "lightgreen"
+	}
+	r.Save()
+	r.SetLineWidth(1)
+	r.SetShadow(shadowColor, 2)
+	r.SetStrokeStyle(borderColor)
+	for _, rg := range sh.selection.ranges {
+		if overlaps(rg, visible) {
+			x, y, w, h := view(rg)
+			r.StrokeRect(x+2, y+2, w-2, h-2)
+		}
+	}
+	// The active cell of a multi-cell range gets its own outline.
+	active := sh.cellRange(sh.selection.active)
+	if rg, ok := sh.selection.current(); ok && rg != active && overlaps(active, visible) {
+		x, y, w, h := view(active)
+		r.StrokeRect(x+3, y+3, w-4, h-4)
+	}
+	r.Restore()
+}
@@ -1,3 +1,7 @@
 module github.com/ajz01/server
 
 go 1.13
+
+require github.com/ajz01/grid v0.0.0
+
+replace github.com/ajz01/grid => ../
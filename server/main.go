@@ -5,7 +5,10 @@ import (
 	"html/template"
 	"io/ioutil"
 	"net/http"
+	"os"
 	"strconv"
+
+	"github.com/ajz01/grid"
 )
 
 type Page struct {
@@ -74,10 +77,57 @@ func wasmHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, string(p.Body[:n]))
 }
 
+// The largest snapshot served, in pixels along either side.
+const maxRenderSize = 4096
+
+// Render a range of the sample sheet as a PNG or SVG snapshot, for
+// example /render?range=A1:F20&format=svg.
+func renderHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Println(r.URL)
+	rg, ok := grid.ParseRange(r.URL.Query().Get("range"))
+	if !ok {
+		http.Error(w, "invalid range", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "png" && format != "svg" {
+		http.Error(w, "unknown format "+format, http.StatusBadRequest)
+		return
+	}
+	f, err := os.Open("../wasm/sheet.json")
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "no sheet", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	sh, err := grid.ReadSheet(f)
+	if err != nil {
+		fmt.Println(err)
+		http.Error(w, "invalid sheet", http.StatusInternalServerError)
+		return
+	}
+	if width, height := sh.RangeSize(rg); width <= 0 || height <= 0 || width > maxRenderSize || height > maxRenderSize {
+		http.Error(w, "range too large", http.StatusBadRequest)
+		return
+	}
+	if format == "svg" {
+		w.Header().Set("content-type", "image/svg+xml")
+		err = sh.WriteSVG(w, rg)
+	} else {
+		w.Header().Set("content-type", "image/png")
+		err = sh.WritePNG(w, rg)
+	}
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
 func main() {
 	http.HandleFunc("/wasm_exec.html", handler)
 	http.HandleFunc("/wasm_exec.js", scriptHandler)
 	http.HandleFunc("/test.wasm.gz", wasmHandler)
+	http.HandleFunc("/render", renderHandler)
 
 	http.ListenAndServe(":8080", nil)
 }
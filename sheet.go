@@ -0,0 +1,258 @@
+package grid
+
+// The model of a grid: the cells with their formulas, styles and
+// formats, the column widths and row heights, the selection, the
+// in-cell editor and the scroll position of the view-port. A Sheet has
+// no DOM or canvas dependencies so it can be used, and tested, outside
+// of the browser. It draws with a Renderer, the grid renders the Sheet
+// onto its canvas and passes the browser events on to it.
+type Sheet struct {
+	x, y          int // scroll position, the grid coordinates of the view-port
+	width, height int // view-port size
+	selection     selection
+	data          map[Address]*cell
+	editCell      *cell
+	deps          depGraph       // formula dependencies
+	formats       formats        // cell display formats
+	cols, rows    axis           // column widths and row heights
+	headerWidth   int            // width of the row headers, zero if hidden
+	headerHeight  int            // height of the column headers, zero if hidden
+	colHeaders    map[int]string // custom column header labels
+	rowHeaders    map[int]string // custom row header labels
+	frozenRows    int
+	frozenCols    int
+	index         cellIndex    // spatial index of the data cells
+	history       history      // undo and redo stacks
+	editor        editor       // the in-cell editor
+	styles        styles       // cell, range, row and column styles
+	merges        []Range      // merged cells
+	listener      cellListener // notified of changed cells, may be nil
+	styler        cellStyler   // changes the styles of drawn cells, may be nil
+}
+
+// Receives the changes to the cells of a Sheet. The grid passes them on
+// to its Container.
+type cellListener interface {
+	AddCell(cell CellContent)
+	AddCellsDone()
+}
+
+// A Container that implements RecalcListener is told of the formula
+// cells recalculated after a change.
+type RecalcListener interface {
+	CellsRecalculated(cells []CellContent)
+}
+
+// Create a Sheet for a view-port of width by height pixels. Columns
+// are colWidth wide and rows rowHeight high unless they are resized.
+func NewSheet(width, height, colWidth, rowHeight int) *Sheet {
+	sh := &Sheet{0, 0, width, height, selection{}, map[Address]*cell{}, nil,
+		newDepGraph(), newFormats(), newAxis(colWidth), newAxis(rowHeight), 0, 0,
+		map[int]string{}, map[int]string{}, 0, 0, cellIndex{}, history{}, editor{},
+		newStyles(), nil, nil, nil}
+	sh.selection.expand = sh.expandMerges
+	return sh
+}
+
+func (sh *Sheet) GetCellContent(row, col int) CellContent {
+	a := Address{row, col}
+	if _, ok := sh.data[a]; !ok {
+		return sh.addData(row, col, "")
+	}
+	return sh.data[a]
+}
+
+func (sh *Sheet) AddData(row, col int, value string) {
+	sh.addData(row, col, value)
+}
+
+func (sh *Sheet) AddValue(row, col int, v Value) {
+	sh.addValue(row, col, v)
+}
+
+func (sh *Sheet) SelectCells(addresses []Address) {
+	for _, a := range addresses {
+		if !sh.selection.contains(a) {
+			sh.selection.add(a)
+		}
+	}
+}
+
+func (sh *Sheet) ClearSelection() {
+	sh.selection.clear()
+}
+
+// Set the display format of a cell. The format only changes how the
+// value is drawn, editing still shows the raw value.
+func (sh *Sheet) SetCellFormat(row, col int, format string) {
+	sh.formats.setCell(Address{row, col}, format)
+}
+
+// Set the display format of a column. Cell and range formats take
+// precedence over the column format.
+func (sh *Sheet) SetColumnFormat(col int, format string) {
+	sh.formats.setColumn(col, format)
+}
+
+// Set the display format of every cell in a range.
+func (sh *Sheet) SetRangeFormat(r Range, format string) {
+	sh.formats.setRange(r, format)
+}
+
+func (sh *Sheet) GetCellFormat(row, col int) string {
+	return sh.formats.get(Address{row, col})
+}
+
+// Set the width of a column in pixels.
+func (sh *Sheet) SetColumnWidth(col, width int) {
+	sh.cols.set(col, width)
+}
+
+// Set the height of a row in pixels.
+func (sh *Sheet) SetRowHeight(row, height int) {
+	sh.rows.set(row, height)
+}
+
+func (sh *Sheet) GetColumnWidth(col int) int {
+	return sh.cols.sizeOf(col)
+}
+
+func (sh *Sheet) GetRowHeight(row int) int {
+	return sh.rows.sizeOf(row)
+}
+
+func (sh *Sheet) GetX() int {
+	return sh.x
+}
+
+func (sh *Sheet) GetY() int {
+	return sh.y
+}
+
+func (sh *Sheet) GetEditCellAddress() *Address {
+	if sh.editCell == nil {
+		return nil
+	}
+	return &Address{sh.editCell.row, sh.editCell.col}
+}
+
+// Convert the grid coordinates to the grid row and col.
+func (sh *Sheet) getLocation(x, y int) (int, int) {
+	row := sh.rows.index(y)
+	col := sh.cols.index(x)
+	return row, col
+}
+
+// The cell at an address. Empty cells that are not in the data, other
+// than the edit cell, are returned as a new cell.
+func (sh *Sheet) cellAt(a Address) *cell {
+	if c, ok := sh.data[a]; ok {
+		return c
+	}
+	if c := sh.editCell; c != nil && c.row == a.Row && c.col == a.Col {
+		return c
+	}
+	return &cell{row: a.Row, col: a.Col, sheet: sh}
+}
+
+// Convert grid coordinates to view-port coordinates.
+func (sh *Sheet) toView(x, y int) (int, int) {
+	fw, fh := sh.frozenSize()
+	if x >= fw {
+		x -= sh.x
+	}
+	if y >= fh {
+		y -= sh.y
+	}
+	return x + sh.headerWidth, y + sh.headerHeight
+}
+
+// Convert row and col values to grid coordinates.
+func (sh *Sheet) addressToCoords(row, col int) (int, int) {
+	x := sh.cols.offset(col)
+	y := sh.rows.offset(row)
+
+	return x, y
+}
+
+// Rebuild the spatial index after cells moved to new addresses.
+func (sh *Sheet) reindex() {
+	sh.index = cellIndex{}
+	for a := range sh.data {
+		sh.index.add(a)
+	}
+}
+
+// Find the range of cells visible in a pane.
+func (sh *Sheet) paneRange(p pane) Range {
+	from := Address{sh.rows.index(p.gy), sh.cols.index(p.gx)}
+	to := Address{sh.rows.index(p.gy + p.h - 1), sh.cols.index(p.gx + p.w - 1)}
+	return Range{from, to}
+}
+
+// Add a value to the cell at the Address of row and col of the grid.
+func (sh *Sheet) addData(row, col int, value string) *cell {
+	var a *cell
+	if c, ok := sh.data[Address{row, col}]; ok {
+		sh.history.record(valueChange{Address{row, col}, c.value, value, true, true})
+		c.value = value
+		sh.data[Address{row, col}] = c
+		a = c
+	} else {
+		sh.history.record(valueChange{Address{row, col}, "", value, false, true})
+		c := cell{row: row, col: col, value: value, sheet: sh}
+		sh.data[Address{row, col}] = &c
+		sh.index.add(Address{row, col})
+		a = &c
+	}
+	sh.cellChanged(a)
+	if sh.listener != nil {
+		sh.listener.AddCell(a)
+	}
+	return a
+}
+
+// Set the raw values of many cells as a single undoable step. The
+// formulas are recalculated once for all of the cells.
+func (sh *Sheet) setValues(values map[Address]string) {
+	sh.history.begin()
+	changes := map[Address]*string{}
+	for a, v := range values {
+		v := v
+		old, ok := "", false
+		if c, found := sh.data[a]; found {
+			old, ok = c.value, true
+		}
+		sh.history.record(valueChange{a, old, v, ok, true})
+		changes[a] = &v
+	}
+	sh.history.end()
+	sh.applyValues(changes)
+}
+
+// Add a typed value to the cell at the Address of row and col.
+func (sh *Sheet) addValue(row, col int, v Value) *cell {
+	return sh.addData(row, col, v.input())
+}
+
+// Convert view-port coordinates to grid coordinates. Frozen rows and
+// columns don't scroll.
+func (sh *Sheet) viewToGrid(vx, vy int) (int, int) {
+	fw, fh := sh.frozenSize()
+	x := vx - sh.headerWidth
+	y := vy - sh.headerHeight
+	if x >= fw {
+		x += sh.x
+	}
+	if y >= fh {
+		y += sh.y
+	}
+	return x, y
+}
+
+// The Address of the cell at view-port coordinates. A point in merged
+// cells is the Address of the first cell of the merge.
+func (sh *Sheet) addressAt(vx, vy int) Address {
+	row, col := sh.getLocation(sh.viewToGrid(vx, vy))
+	return sh.cellRange(Address{row, col}).From
+}
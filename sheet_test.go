@@ -0,0 +1,300 @@
+package grid
+
+import (
+	"testing"
+)
+
+func TestAddressAt(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	sh.headerWidth, sh.headerHeight = 50, 20
+	sh.FreezePanes(1, 1)
+	sh.setView(250, 40)
+	sh.merges = []Range{r1("F5:G6")}
+	tests := []struct {
+		vx, vy int
+		want   string
+	}{
+		{60, 30, "A1"},  // frozen corner
+		{160, 30, "D1"}, // frozen row, scrolled right
+		{60, 50, "A4"},  // frozen column, scrolled down
+		{160, 50, "D4"}, // main pane
+		{560, 70, "H5"}, // next to the merge
+		{460, 70, "F5"}, // inside the merge
+		{360, 90, "F5"}, // inside the merge
+	}
+	for _, tt := range tests {
+		if got := sh.addressAt(tt.vx, tt.vy); got != a1(tt.want) {
+			t.Errorf("addressAt(%d, %d) = %s, want %s", tt.vx, tt.vy, got, tt.want)
+		}
+	}
+}
+
+func TestNavigate(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	for row := 0; row < 3; row++ {
+		sh.addData(row, 0, "x")
+	}
+	sh.merges = []Range{r1("B2:C3")}
+	sh.selection.set(a1("A1"))
+	tests := []struct {
+		key         string
+		shift, ctrl bool
+		selected    string
+		active      string
+	}{
+		{"ArrowRight", false, false, "B1", "B1"},
+		{"ArrowLeft", false, false, "A1", "A1"},
+		{"ArrowDown", false, true, "A3", "A3"},
+		{"ArrowUp", true, false, "A2:A3", "A3"},
+		{"ArrowRight", true, false, "A2:C3", "A3"},
+		{"ArrowUp", false, false, "A2", "A2"},
+		{"ArrowRight", false, false, "B2:C3", "B2"},
+		{"ArrowRight", false, false, "D2", "D2"},
+		{"Tab", true, false, "B2:C3", "B2"},
+		{"Enter", false, false, "B4", "B4"},
+		{"Home", false, false, "A4", "A4"},
+	}
+	for _, tt := range tests {
+		sh.navigate(tt.key, tt.shift, tt.ctrl)
+		r, _ := sh.selection.current()
+		if r != r1(tt.selected) || sh.selection.active != a1(tt.active) {
+			t.Fatalf("%s shift %v ctrl %v: selected %s active %s, want %s %s",
+				tt.key, tt.shift, tt.ctrl, r, sh.selection.active, tt.selected, tt.active)
+		}
+	}
+}
+
+func TestScrollTo(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	sh.scrollTo(a1("A101"))
+	if sh.x != 0 || sh.y != 1420 {
+		t.Errorf("scroll position = %d, %d, want 0, 1420", sh.x, sh.y)
+	}
+	if first, last := sh.visibleRows(); first != 71 || last != 101 {
+		t.Errorf("visible rows = %d-%d, want 71-101", first, last)
+	}
+	sh.scrollTo(a1("A1"))
+	if sh.y != 0 {
+		t.Errorf("scroll position = %d, %d, want 0, 0", sh.x, sh.y)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	sh.addData(0, 0, "4")
+	sh.selection.set(a1("B1"))
+	sh.startEdit(false)
+	for _, key := range []string{"=", "A", "1", "*", "3", "Backspace", "2"} {
+		sh.editKey(key, false)
+	}
+	if got := sh.data[a1("B1")]; got != nil {
+		t.Errorf("B1 = %q before the edit is committed", got.value)
+	}
+	sh.editKey("Enter", false)
+	if c := sh.data[a1("B1")]; c == nil || c.value != "=A1*2" || c.val != NumberVal(8) {
+		t.Fatalf("B1 = %+v, want =A1*2 with the value 8", c)
+	}
+	if sh.selection.active != a1("B2") || sh.editCell != nil {
+		t.Errorf("active = %s, editing %v, want B2 and not editing", sh.selection.active, sh.editCell != nil)
+	}
+
+	// Escape keeps the value.
+	sh.selection.set(a1("B1"))
+	sh.startEdit(true)
+	sh.editKey("Backspace", false)
+	sh.editKey("Escape", false)
+	if c := sh.data[a1("B1")]; c.value != "=A1*2" {
+		t.Errorf("B1 = %q after escape, want =A1*2", c.value)
+	}
+
+	sh.Undo()
+	if _, ok := sh.data[a1("B1")]; ok {
+		t.Errorf("B1 exists after undo")
+	}
+	sh.Redo()
+	if c := sh.data[a1("B1")]; c == nil || c.val != NumberVal(8) {
+		t.Errorf("B1 = %+v after redo, want 8", c)
+	}
+}
+
+func TestPasteRows(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	sh.addData(3, 3, "old")
+	sh.selection.set(a1("C3"))
+	sh.pasteRows([][]string{{"a", "b"}, {"c", ""}})
+	want := map[string]string{"C3": "a", "D3": "b", "C4": "c", "D4": ""}
+	for s, v := range want {
+		if c, ok := sh.data[a1(s)]; !ok || c.value != v {
+			t.Errorf("cell %s = %+v, want %q", s, c, v)
+		}
+	}
+	if got := sh.GetSelection(); len(got) != 1 || got[0] != r1("C3:D4") {
+		t.Errorf("selection = %v, want C3:D4", got)
+	}
+	sh.Undo()
+	if c := sh.data[a1("D4")]; c == nil || c.value != "old" || len(sh.data) != 1 {
+		t.Errorf("cells after undo = %d, D4 = %+v, want only D4 = old", len(sh.data), c)
+	}
+}
+
+// A merge and the values it clears are undone and redone together.
+func TestMergeUndo(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	sh.addData(0, 0, "keep")
+	sh.addData(0, 1, "cleared")
+	sh.history = history{}
+
+	sh.MergeCells(r1("A1:B1"))
+	if sh.filled(a1("B1")) {
+		t.Errorf("B1 wasn't cleared")
+	}
+	sh.Undo()
+	if len(sh.merges) != 0 {
+		t.Errorf("merges after undo = %v", sh.merges)
+	}
+	if c, ok := sh.data[a1("B1")]; !ok || c.value != "cleared" {
+		t.Errorf("B1 after undo = %+v, want cleared", c)
+	}
+	if sh.CanUndo() {
+		t.Errorf("the merge was more than one step")
+	}
+	sh.Redo()
+	if m := sh.GetMerges(); len(m) != 1 || m[0] != r1("A1:B1") {
+		t.Errorf("merges after redo = %v, want A1:B1", m)
+	}
+	if sh.filled(a1("B1")) {
+		t.Errorf("B1 wasn't cleared again")
+	}
+
+	sh.UnmergeCells(r1("B1"))
+	sh.UnmergeCells(r1("D4"))
+	sh.Undo()
+	if m := sh.GetMerges(); len(m) != 1 || m[0] != r1("A1:B1") {
+		t.Errorf("merges after undoing the unmerge = %v, want A1:B1", m)
+	}
+}
+
+// Ctrl+Z while editing undoes the typing, not the committed changes.
+func TestEditUndo(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	sh.selection.set(a1("A1"))
+	sh.startEdit(false)
+	sh.editKey("1", false)
+	sh.commitEdit()
+
+	sh.selection.set(a1("A2"))
+	sh.startEdit(false)
+	sh.editKey("a", false)
+	sh.editKey("b", false)
+	sh.undoKey(false)
+	if sh.editCell == nil || sh.editor.text != "a" {
+		t.Fatalf("editor text after undo = %q, want a", sh.editor.text)
+	}
+	sh.undoKey(true)
+	if sh.editor.text != "ab" {
+		t.Errorf("editor text after redo = %q, want ab", sh.editor.text)
+	}
+	sh.undoKey(false)
+	sh.undoKey(false)
+	sh.undoKey(false)
+	if sh.editCell == nil || sh.editor.text != "" {
+		t.Errorf("editor text = %q, want the edit to go on empty", sh.editor.text)
+	}
+	if c, ok := sh.data[a1("A1")]; !ok || c.value != "1" {
+		t.Errorf("A1 = %+v, the committed change was undone", c)
+	}
+
+	sh.cancelEdit()
+	sh.undoKey(false)
+	if sh.filled(a1("A1")) {
+		t.Errorf("A1 wasn't undone after the edit ended")
+	}
+}
+
+func TestResizeBorderAt(t *testing.T) {
+	type result struct {
+		column bool
+		index  int
+		ok     bool
+	}
+	tests := []struct {
+		headers bool
+		vx, vy  int
+		want    result
+	}{
+		// The borders are dragged in the headers.
+		{true, 150, 10, result{true, 0, true}},
+		{true, 250, 10, result{true, 1, true}},
+		{true, 20, 41, result{false, 0, true}},
+		{true, 150, 50, result{}},
+		{true, 120, 10, result{}},
+		// Between the cells without headers.
+		{false, 100, 50, result{true, 0, true}},
+		{false, 50, 41, result{false, 1, true}},
+		{false, 50, 50, result{}},
+	}
+	for _, tt := range tests {
+		sh := NewSheet(800, 600, 100, 20)
+		if tt.headers {
+			sh.headerWidth, sh.headerHeight = rowHeaderWidth, 20
+		}
+		column, index, ok := sh.resizeBorderAt(tt.vx, tt.vy)
+		if got := (result{column, index, ok}); got != tt.want {
+			t.Errorf("headers %v: resizeBorderAt(%d, %d) = %+v, want %+v", tt.headers, tt.vx, tt.vy, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateGroup(t *testing.T) {
+	sh := NewSheet(800, 600, 100, 20)
+	value := func(ref string) string {
+		return sh.cellAt(a1(ref)).value
+	}
+	sh.addData(0, 0, "1")
+	sh.BeginUpdate()
+	sh.addData(0, 0, "2")
+	sh.addData(1, 0, "=A1*10")
+	// Nested groups are part of the outer step.
+	sh.BeginUpdate()
+	sh.addData(2, 0, "x")
+	sh.EndUpdate()
+	if sh.history.depth != 1 || len(sh.history.undo) != 1 {
+		t.Errorf("the inner group ended the step")
+	}
+	sh.EndUpdate()
+	sh.addData(3, 0, "y")
+
+	sh.Undo()
+	if value("A4") != "" || value("A3") != "x" {
+		t.Errorf("undo undid more than the last step")
+	}
+	sh.Undo()
+	if value("A1") != "1" || value("A2") != "" || value("A3") != "" {
+		t.Errorf("undo of the group = %q %q %q, want 1 and empty cells", value("A1"), value("A2"), value("A3"))
+	}
+	if _, ok := sh.data[a1("A2")]; ok {
+		t.Errorf("undo kept the cell the group created")
+	}
+	sh.Redo()
+	if value("A1") != "2" || value("A3") != "x" || sh.cellAt(a1("A2")).val != NumberVal(20) {
+		t.Errorf("redo of the group = %q %v %q", value("A1"), sh.cellAt(a1("A2")).val, value("A3"))
+	}
+	sh.Undo()
+	sh.Undo()
+	if sh.CanUndo() || !sh.CanRedo() {
+		t.Errorf("CanUndo = %v, CanRedo = %v after undoing everything", sh.CanUndo(), sh.CanRedo())
+	}
+	// A new change clears the redo steps.
+	sh.addData(0, 1, "z")
+	if sh.CanRedo() {
+		t.Errorf("redo steps were kept after a change")
+	}
+
+	// An empty group is not a step and an unmatched end is ignored.
+	sh.BeginUpdate()
+	sh.EndUpdate()
+	sh.EndUpdate()
+	if n := len(sh.history.undo); n != 1 {
+		t.Errorf("%d steps, want 1", n)
+	}
+}
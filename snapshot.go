@@ -0,0 +1,50 @@
+package grid
+
+import (
+	"image"
+	"image/png"
+	"io"
+)
+
+// The size in pixels of a snapshot of a range, the cells with the grid
+// lines around them.
+func (sh *Sheet) RangeSize(r Range) (int, int) {
+	r = NewRange(r.From, r.To)
+	w := sh.cols.offset(r.To.Col+1) - sh.cols.offset(r.From.Col)
+	h := sh.rows.offset(r.To.Row+1) - sh.rows.offset(r.From.Row)
+	return w + 1, h + 1
+}
+
+// Draw a snapshot of a range: its cells the way the grid draws them,
+// without the headers, frozen panes or selection. The top left grid
+// lines of the range are at 0, 0.
+func (sh *Sheet) RenderRange(rd Renderer, r Range) {
+	r = NewRange(r.From, r.To)
+	view := *sh
+	// The view starts a pixel before the range so the grid lines on
+	// its top and left edges are drawn.
+	view.x = sh.cols.offset(r.From.Col) - 1
+	view.y = sh.rows.offset(r.From.Row) - 1
+	view.width, view.height = sh.RangeSize(r)
+	view.headerWidth, view.headerHeight = 0, 0
+	view.frozenRows, view.frozenCols = 0, 0
+	view.selection = selection{}
+	view.editCell = nil
+	view.Render(rd)
+}
+
+// Write a snapshot of a range as a PNG image.
+func (sh *Sheet) WritePNG(w io.Writer, r Range) error {
+	width, height := sh.RangeSize(r)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	sh.RenderRange(NewImageRenderer(img), r)
+	return png.Encode(w, img)
+}
+
+// Write a snapshot of a range as an SVG document.
+func (sh *Sheet) WriteSVG(w io.Writer, r Range) error {
+	rd := NewSVGRenderer(sh.RangeSize(r))
+	sh.RenderRange(rd, r)
+	_, err := rd.WriteTo(w)
+	return err
+}
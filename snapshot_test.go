@@ -0,0 +1,131 @@
+package grid
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDocument = `{
+	"cellWidth": 70, "cellHeight": 20,
+	"cells": {
+		"A1": "Fruit", "B1": "Amount", "C1": "Share",
+		"A2": "Apples", "B2": "12.5", "C2": "=B2/B4",
+		"A3": "Pears & plums", "B3": "37.5", "C3": "=B3/B4",
+		"A4": "Total", "B4": "=SUM(B2:B3)",
+		"A6": "Notes that are longer than the cell",
+		"E6": "outside"
+	},
+	"columnWidths": {"A": 110},
+	"rowHeights": {"6": 30},
+	"formats": [{"range": "B2:B4", "format": "#,##0.00"}, {"range": "C2:C3", "format": "0%"}],
+	"styles": [
+		{"range": "A1:C1", "style": {"background": "#eeeeee", "bold": true, "alignment": {"horizontal": "center"}}},
+		{"range": "A4:C4", "style": {"borders": {"top": {"color": "black", "width": 2}}}},
+		{"range": "A6", "style": {"color": "rgba(0, 0, 255, 0.5)", "alignment": {"vertical": "middle"}}}
+	],
+	"merges": ["A5:C5"]
+}`
+
+func TestReadSheet(t *testing.T) {
+	sh, err := ReadSheet(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := sh.data[a1("B4")].val.Number(); n != 50 {
+		t.Errorf("B4 = %v, want 50", sh.data[a1("B4")].val)
+	}
+	if got := sh.data[a1("C2")].text(); got != "25%" {
+		t.Errorf("C2 = %q, want 25%%", got)
+	}
+	if sh.GetColumnWidth(0) != 110 || sh.GetRowHeight(5) != 30 {
+		t.Errorf("sizes = %d, %d, want 110, 30", sh.GetColumnWidth(0), sh.GetRowHeight(5))
+	}
+	if s := sh.GetCellStyle(0, 1); s.FontWeight != "bold" || s.Alignment.Horizontal != AlignCenter {
+		t.Errorf("B1 style = %+v", s)
+	}
+	if len(sh.merges) != 1 || sh.CanUndo() {
+		t.Errorf("merges = %v, can undo %v", sh.merges, sh.CanUndo())
+	}
+	for _, doc := range []string{
+		`{"cells": {"1A": "x"}}`,
+		`{"columnWidths": {"A1": 10}}`,
+		`{"styles": [{"range": "A1", "style": {"alignment": {"horizontal": "justify"}}}]}`,
+		`{"merges": ["A1:"]}`,
+	} {
+		if _, err := ReadSheet(strings.NewReader(doc)); err == nil {
+			t.Errorf("ReadSheet(%s) succeeded", doc)
+		}
+	}
+}
+
+// The snapshots are compared to golden files, run the test with
+// -update to write them again.
+func TestSnapshot(t *testing.T) {
+	sh, err := ReadSheet(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := r1("A1:D6")
+	if w, h := sh.RangeSize(r); w != 321 || h != 131 {
+		t.Errorf("size = %d, %d, want 321, 131", w, h)
+	}
+
+	var b bytes.Buffer
+	if err := sh.WritePNG(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 321, 131) {
+		t.Errorf("image bounds = %v", img.Bounds())
+	}
+	goldenImage(t, "snapshot.png", img)
+
+	b.Reset()
+	if err := sh.WriteSVG(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	svg := b.String()
+	for _, s := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="321" height="131"`,
+		`>Pears &amp; plums</text>`,
+		`>50.00</text>`,
+		`font-weight="bold"`,
+		`fill="#0000ff" fill-opacity="0.502"`,
+	} {
+		if !strings.Contains(svg, s) {
+			t.Errorf("svg doesn't contain %s", s)
+		}
+	}
+	if strings.Contains(svg, "outside") {
+		t.Errorf("svg contains a cell outside of the range")
+	}
+	golden(t, "snapshot.svg", b.Bytes())
+}
+
+// Compare output to a golden file in testdata, or write the file with
+// -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s", path)
+	}
+}
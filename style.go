@@ -0,0 +1,201 @@
+package grid
+
+import (
+	"strconv"
+)
+
+// The style of a cell. Zero fields are not set and fall back to the
+// style of the next level: a cell style takes precedence over the
+// latest range style containing the cell, a range style over a row
+// style and a row style over a column style. Fields that are set at no
+// level use the grid defaults.
+type CellStyle struct {
+	Background string // fill color
+	FontFamily string
+	FontSize   int    // pixels
+	FontWeight string // "normal", "bold" or a number such as "600"
+	FontStyle  string // "normal" or "italic"
+	Color      string // text color
+	Padding    int    // pixels between the borders and the text
+	Alignment  Alignment
+	Borders    Borders
+}
+
+// The grid defaults for the fields of a CellStyle.
+var defaultStyle = CellStyle{
+	Background: "white",
+	FontFamily: "arial",
+	FontSize:   15,
+	FontWeight: "normal",
+	FontStyle:  "normal",
+	Color:      "black",
+	Padding:    2,
+}
+
+// Override the fields of s that are set in o.
+func (s CellStyle) overlay(o CellStyle) CellStyle {
+	if o.Background != "" {
+		s.Background = o.Background
+	}
+	if o.FontFamily != "" {
+		s.FontFamily = o.FontFamily
+	}
+	if o.FontSize != 0 {
+		s.FontSize = o.FontSize
+	}
+	if o.FontWeight != "" {
+		s.FontWeight = o.FontWeight
+	}
+	if o.FontStyle != "" {
+		s.FontStyle = o.FontStyle
+	}
+	if o.Color != "" {
+		s.Color = o.Color
+	}
+	if o.Padding != 0 {
+		s.Padding = o.Padding
+	}
+	if o.Alignment != (Alignment{}) {
+		s.Alignment = o.Alignment
+	}
+	s.Borders = s.Borders.overlay(o.Borders)
+	return s
+}
+
+// The canvas font of the style.
+func (s CellStyle) font() string {
+	return s.FontStyle + " " + s.FontWeight + " " + strconv.Itoa(s.FontSize) + "px " + s.FontFamily
+}
+
+// The styles of the grid by level.
+type styles struct {
+	cells   map[Address]CellStyle
+	ranges  []rangeStyle
+	rows    map[int]CellStyle
+	columns map[int]CellStyle
+}
+
+type rangeStyle struct {
+	r     Range
+	style CellStyle
+}
+
+func newStyles() styles {
+	return styles{map[Address]CellStyle{}, []rangeStyle{}, map[int]CellStyle{}, map[int]CellStyle{}}
+}
+
+func (st *styles) copy() styles {
+	c := newStyles()
+	for a, s := range st.cells {
+		c.cells[a] = s
+	}
+	c.ranges = append(c.ranges, st.ranges...)
+	for i, s := range st.rows {
+		c.rows[i] = s
+	}
+	for i, s := range st.columns {
+		c.columns[i] = s
+	}
+	return c
+}
+
+func (st *styles) empty() bool {
+	return len(st.cells) == 0 && len(st.ranges) == 0 && len(st.rows) == 0 && len(st.columns) == 0
+}
+
+// The style of the cell at a resolved from all the levels.
+func (st *styles) get(a Address) CellStyle {
+	s := defaultStyle.overlay(st.columns[a.Col]).overlay(st.rows[a.Row])
+	for _, rs := range st.ranges {
+		if rs.r.Contains(a) {
+			s = s.overlay(rs.style)
+		}
+	}
+	return s.overlay(st.cells[a])
+}
+
+func (st *styles) setCell(a Address, s CellStyle) {
+	if s == (CellStyle{}) {
+		delete(st.cells, a)
+		return
+	}
+	st.cells[a] = s
+}
+
+func (st *styles) setRow(row int, s CellStyle) {
+	st.rows[row] = s
+}
+
+func (st *styles) setColumn(col int, s CellStyle) {
+	st.columns[col] = s
+}
+
+// Add a range style. The fields it sets are also set on the cell styles
+// inside the range so the range style shows on those cells.
+func (st *styles) setRange(r Range, s CellStyle) {
+	for a, cs := range st.cells {
+		if r.Contains(a) {
+			st.cells[a] = cs.overlay(s)
+		}
+	}
+	// Drop ranges the new range completely covers and overrides.
+	ranges := st.ranges[:0]
+	for _, rs := range st.ranges {
+		if !(r.Contains(rs.r.From) && r.Contains(rs.r.To) && s.overlay(rs.style) == s) {
+			ranges = append(ranges, rs)
+		}
+	}
+	st.ranges = append(ranges, rangeStyle{r, s})
+}
+
+// Set the style of a cell, replacing its previous cell style.
+func (sh *Sheet) SetCellStyle(row, col int, s CellStyle) {
+	sh.styles.setCell(Address{row, col}, s)
+}
+
+// Set the style of a row.
+func (sh *Sheet) SetRowStyle(row int, s CellStyle) {
+	sh.styles.setRow(row, s)
+}
+
+// Set the style of a column.
+func (sh *Sheet) SetColumnStyle(col int, s CellStyle) {
+	sh.styles.setColumn(col, s)
+}
+
+// Set the style of a range of cells. The fields set in s override the
+// styles of the cells inside the range.
+func (sh *Sheet) SetRangeStyle(r Range, s CellStyle) {
+	sh.styles.setRange(NewRange(r.From, r.To), s)
+}
+
+// The style a cell is drawn with, resolved from the cell, range, row
+// and column styles and the defaults.
+func (sh *Sheet) GetCellStyle(row, col int) CellStyle {
+	return sh.styles.get(Address{row, col})
+}
+
+// The empty cells of a range that have a background. They are drawn
+// even though they have no data.
+func (sh *Sheet) styledCells(r Range) []Address {
+	addresses := []Address{}
+	if sh.styles.empty() {
+		return addresses
+	}
+	for row := r.From.Row; row <= r.To.Row; row++ {
+		for col := r.From.Col; col <= r.To.Col; col++ {
+			a := Address{row, col}
+			if _, ok := sh.data[a]; ok {
+				continue
+			}
+			if c := sh.editCell; c != nil && a == (Address{c.row, c.col}) {
+				continue
+			}
+			s := sh.styles.get(a)
+			if s.Background != defaultStyle.Background {
+				addresses = append(addresses, a)
+			}
+		}
+	}
+	return addresses
+}
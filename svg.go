@@ -0,0 +1,269 @@
+package grid
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Renderer writing an SVG document. The text is left to the SVG
+// viewer's fonts, so it is measured with the widths of Helvetica, which
+// is close to Arial and the other sans-serif fonts. Shadows are not
+// drawn.
+type SVGRenderer struct {
+	width, height int
+	body          bytes.Buffer
+	clips         map[image.Rectangle]string // clipPath ids by rectangle
+	defs          bytes.Buffer
+	state         svgState
+	saved         []svgState
+	path          bytes.Buffer
+}
+
+// The drawing state saved and restored by Save and Restore.
+type svgState struct {
+	clip      image.Rectangle
+	fillStyle string
+	fill      string
+	stroke    string
+	lineWidth float64
+	dash      []float64
+	font      cssFont
+}
+
+// Create a Renderer for an SVG document of width by height pixels.
+func NewSVGRenderer(width, height int) *SVGRenderer {
+	return &SVGRenderer{width: width, height: height, clips: map[image.Rectangle]string{},
+		state: svgState{
+			clip:      image.Rect(0, 0, width, height),
+			fillStyle: "#000000",
+			fill:      svgColor("black", "fill"),
+			stroke:    svgColor("black", "stroke"),
+			lineWidth: 1,
+			font:      parseFont(""),
+		}}
+}
+
+// Write the SVG document.
+func (r *SVGRenderer) WriteTo(w io.Writer) (int64, error) {
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" xml:space="preserve">`+"\n",
+		r.width, r.height, r.width, r.height)
+	if r.defs.Len() > 0 {
+		doc.WriteString("<defs>\n")
+		doc.Write(r.defs.Bytes())
+		doc.WriteString("</defs>\n")
+	}
+	doc.Write(r.body.Bytes())
+	doc.WriteString("</svg>\n")
+	return doc.WriteTo(w)
+}
+
+func (r *SVGRenderer) Save() {
+	r.saved = append(r.saved, r.state)
+}
+
+func (r *SVGRenderer) Restore() {
+	if n := len(r.saved); n > 0 {
+		r.state = r.saved[n-1]
+		r.saved = r.saved[:n-1]
+	}
+}
+
+func (r *SVGRenderer) Clip(x, y, w, h int) {
+	r.state.clip = r.state.clip.Intersect(image.Rect(x, y, x+w, y+h))
+}
+
+func (r *SVGRenderer) SetFont(font string) {
+	r.state.font = parseFont(font)
+}
+
+// Colors that are not recognized are ignored, like the canvas does.
+func (r *SVGRenderer) SetFillStyle(c string) {
+	if _, ok := parseColor(c); ok {
+		r.state.fillStyle = c
+		r.state.fill = svgColor(c, "fill")
+	}
+}
+
+func (r *SVGRenderer) FillStyle() string {
+	return r.state.fillStyle
+}
+
+func (r *SVGRenderer) SetStrokeStyle(c string) {
+	if _, ok := parseColor(c); ok {
+		r.state.stroke = svgColor(c, "stroke")
+	}
+}
+
+func (r *SVGRenderer) SetLineWidth(width float64) {
+	if width > 0 {
+		r.state.lineWidth = width
+	}
+}
+
+func (r *SVGRenderer) SetLineDash(dash []float64) {
+	r.state.dash = append([]float64(nil), dash...)
+}
+
+// Shadows are not drawn.
+func (r *SVGRenderer) SetShadow(color string, blur float64) {
+}
+
+func (r *SVGRenderer) FillRect(x, y, w, h int) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	r.element("rect", fmt.Sprintf(`x="%d" y="%d" width="%d" height="%d" %s`, x, y, w, h, r.state.fill), "")
+}
+
+func (r *SVGRenderer) StrokeRect(x, y, w, h int) {
+	r.element("rect", fmt.Sprintf(`x="%d" y="%d" width="%d" height="%d" fill="none" %s`, x, y, w, h, r.strokeAttrs()), "")
+}
+
+func (r *SVGRenderer) BeginPath() {
+	r.path.Reset()
+}
+
+func (r *SVGRenderer) MoveTo(x, y float64) {
+	fmt.Fprintf(&r.path, "M%s %s", svgNumber(x), svgNumber(y))
+}
+
+func (r *SVGRenderer) LineTo(x, y float64) {
+	fmt.Fprintf(&r.path, "L%s %s", svgNumber(x), svgNumber(y))
+}
+
+func (r *SVGRenderer) Stroke() {
+	if r.path.Len() == 0 {
+		return
+	}
+	r.element("path", fmt.Sprintf(`d="%s" fill="none" %s`, r.path.String(), r.strokeAttrs()), "")
+}
+
+// The text is placed on its baseline, the ascent of the font below y.
+func (r *SVGRenderer) FillText(text string, x, y int, align HAlign) {
+	if text == "" {
+		return
+	}
+	f := r.state.font
+	anchor := "start"
+	switch align {
+	case AlignCenter:
+		anchor = "middle"
+	case AlignRight:
+		anchor = "end"
+	}
+	r.element("text", fmt.Sprintf(`x="%d" y="%s" font-family="%s" font-size="%s" font-weight="%s" font-style="%s" text-anchor="%s" %s`,
+		x, svgNumber(float64(y)+f.size*fontAscent), escapeXML(f.family), svgNumber(f.size),
+		f.weight, f.style, anchor, r.state.fill), escapeXML(text))
+}
+
+func (r *SVGRenderer) MeasureText(text string) float64 {
+	return r.state.font.width(text)
+}
+
+func (r *SVGRenderer) LineHeight() int {
+	return int(math.Ceil(r.state.font.size * (fontAscent + fontDescent)))
+}
+
+// Write an element clipped to the clip region of the state.
+func (r *SVGRenderer) element(tag, attrs, content string) {
+	if r.state.clip.Empty() {
+		return
+	}
+	fmt.Fprintf(&r.body, "<%s %s", tag, attrs)
+	if r.state.clip != image.Rect(0, 0, r.width, r.height) {
+		id, ok := r.clips[r.state.clip]
+		if !ok {
+			id = "clip" + strconv.Itoa(len(r.clips))
+			c := r.state.clip
+			fmt.Fprintf(&r.defs, `<clipPath id="%s"><rect x="%d" y="%d" width="%d" height="%d"/></clipPath>`+"\n",
+				id, c.Min.X, c.Min.Y, c.Dx(), c.Dy())
+			r.clips[r.state.clip] = id
+		}
+		fmt.Fprintf(&r.body, ` clip-path="url(#%s)"`, id)
+	}
+	if content == "" {
+		r.body.WriteString("/>\n")
+	} else {
+		fmt.Fprintf(&r.body, ">%s</%s>\n", content, tag)
+	}
+}
+
+func escapeXML(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (r *SVGRenderer) strokeAttrs() string {
+	attrs := r.state.stroke + ` stroke-width="` + svgNumber(r.state.lineWidth) + `"`
+	if len(r.state.dash) > 0 {
+		dash := []string{}
+		for _, d := range r.state.dash {
+			dash = append(dash, svgNumber(d))
+		}
+		attrs += ` stroke-dasharray="` + strings.Join(dash, " ") + `"`
+	}
+	return attrs
+}
+
+// The attributes of a color for a fill or stroke attribute. The alpha
+// of the color is written as the opacity.
+func svgColor(s, attr string) string {
+	c, _ := parseColor(s)
+	v := fmt.Sprintf(`%s="#%02x%02x%02x"`, attr, c.R, c.G, c.B)
+	if c.A != 255 {
+		v += fmt.Sprintf(` %s-opacity="%s"`, attr, svgNumber(float64(c.A)/255))
+	}
+	return v
+}
+
+func svgNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
+
+// The ascent and descent of Helvetica as a fraction of the font size.
+const (
+	fontAscent  = 0.905
+	fontDescent = 0.212
+)
+
+// The width of text in the font, from the widths of the Helvetica
+// glyphs. Bold text is a little wider.
+func (f cssFont) width(text string) float64 {
+	units := 0
+	for _, r := range text {
+		switch {
+		case zeroWidth(r):
+		case r >= ' ' && r <= '~':
+			units += helveticaWidths[r-' ']
+		default:
+			units += 556
+		}
+	}
+	w := float64(units) * f.size / 1000
+	if f.bold() {
+		w *= 1.08
+	}
+	return w
+}
+
+// The advance widths of the printable ASCII characters of Helvetica in
+// thousandths of the font size.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // 0 to 9
+	278, 278, 584, 584, 584, 556, 1015, // : to @
+	667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, // A to M
+	722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // N to Z
+	278, 278, 278, 469, 556, 333, // [ to `
+	556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, // a to m
+	556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, // n to z
+	334, 260, 334, 584, // { to ~
+}
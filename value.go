@@ -0,0 +1,329 @@
+package grid
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The kind of a cell value.
+type ValueKind int
+
+const (
+	EmptyKind ValueKind = iota
+	NumberKind
+	TextKind
+	BoolKind
+	DateKind
+	ErrorKind
+)
+
+// Error values.
+const (
+	errDiv0  = "#DIV/0!"
+	errValue = "#VALUE!"
+	errRef   = "#REF!"
+	errName  = "#NAME?"
+	errNA    = "#N/A"
+	errParse = "#ERROR!"
+	errCirc  = "#CIRC!"
+)
+
+var errorValues = []string{errDiv0, errValue, errRef, errName, errNA, errParse, errCirc}
+
+// A typed cell value. Dates are stored as a serial number of days
+// since 1899-12-30 so they can take part in arithmetic and sort with
+// numbers, the same as other spreadsheets.
+type Value struct {
+	kind ValueKind
+	num  float64
+	str  string
+	b    bool
+}
+
+// The day that has the date serial number zero.
+var epoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Create a number value. NaN and infinity are #VALUE! errors.
+func NumberVal(n float64) Value {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return ErrorVal(errValue)
+	}
+	return Value{kind: NumberKind, num: n}
+}
+
+func TextVal(s string) Value {
+	if s == "" {
+		return Value{}
+	}
+	return Value{kind: TextKind, str: s}
+}
+
+func BoolVal(b bool) Value {
+	return Value{kind: BoolKind, b: b}
+}
+
+// Create a date value. The time zone is ignored, the wall clock time
+// is stored.
+func DateVal(t time.Time) Value {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	// Seconds don't overflow like a Duration for dates far from epoch.
+	secs := float64(wall.Unix()-epoch.Unix()) + float64(wall.Nanosecond())/1e9
+	return Value{kind: DateKind, num: secs / (24 * 60 * 60)}
+}
+
+func ErrorVal(e string) Value {
+	return Value{kind: ErrorKind, str: e}
+}
+
+func (v Value) Kind() ValueKind {
+	return v.kind
+}
+
+// The numeric value. Dates return their serial number and booleans
+// one or zero. The second result is false if the value is not numeric.
+func (v Value) Number() (float64, bool) {
+	switch v.kind {
+	case NumberKind, DateKind:
+		return v.num, true
+	case BoolKind:
+		if v.b {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func (v Value) Bool() (bool, bool) {
+	return v.b, v.kind == BoolKind
+}
+
+// The time of a date value.
+func (v Value) Time() (time.Time, bool) {
+	if v.kind != DateKind {
+		return time.Time{}, false
+	}
+	return serialTime(v.num), true
+}
+
+// The time of a date serial number, to the millisecond.
+func serialTime(n float64) time.Time {
+	// The days and the time of day are added separately since a
+	// Duration overflows after about 290 years.
+	days := math.Floor(n)
+	ms := math.Round((n - days) * 24 * 60 * 60 * 1000)
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration(ms) * time.Millisecond)
+}
+
+// The text of a text or error value.
+func (v Value) Text() string {
+	if v.kind == TextKind || v.kind == ErrorKind {
+		return v.str
+	}
+	return ""
+}
+
+// The text shown in the cell for a value.
+func (v Value) String() string {
+	switch v.kind {
+	case NumberKind:
+		return strconv.FormatFloat(v.num, 'g', 15, 64)
+	case BoolKind:
+		if v.b {
+			return "TRUE"
+		}
+		return "FALSE"
+	case DateKind:
+		t, _ := v.Time()
+		switch {
+		case v.num < 1 && v.num >= 0:
+			return t.Format("15:04:05")
+		case v.num == math.Floor(v.num):
+			return t.Format("2006-01-02")
+		}
+		return t.Format("2006-01-02 15:04:05")
+	case TextKind, ErrorKind:
+		return v.str
+	}
+	return ""
+}
+
+// The text a user would enter to get the value. Text that would be
+// detected as another kind is prefixed with an apostrophe.
+func (v Value) input() string {
+	switch v.kind {
+	case NumberKind:
+		return strconv.FormatFloat(v.num, 'f', -1, 64)
+	case TextKind:
+		if p := ParseValue(v.str); p.kind != TextKind || p.str != v.str || isFormula(v.str) {
+			return "'" + v.str
+		}
+	}
+	return v.String()
+}
+
+// The date and time layouts recognized when text is entered.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"1/2/2006",
+	"1/2/2006 15:04",
+	"1/2/2006 15:04:05",
+}
+
+var timeLayouts = []string{
+	"15:04",
+	"15:04:05",
+}
+
+// Detect the type of text entered in a cell. Numbers may use comma
+// thousands separators or end in a percent sign. A leading apostrophe
+// forces the rest of the text to be text.
+func ParseValue(s string) Value {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return Value{}
+	}
+	if s[0] == '\'' {
+		return TextVal(s[1:])
+	}
+	if n, ok := parseNumber(t); ok {
+		return NumberVal(n)
+	}
+	switch strings.ToUpper(t) {
+	case "TRUE":
+		return BoolVal(true)
+	case "FALSE":
+		return BoolVal(false)
+	}
+	for _, e := range errorValues {
+		if strings.ToUpper(t) == e {
+			return ErrorVal(e)
+		}
+	}
+	for _, l := range dateLayouts {
+		if d, err := time.Parse(l, t); err == nil {
+			return DateVal(d)
+		}
+	}
+	for _, l := range timeLayouts {
+		if d, err := time.Parse(l, t); err == nil {
+			return Value{kind: DateKind, num: (float64(d.Hour()) + float64(d.Minute())/60 + float64(d.Second())/3600) / 24}
+		}
+	}
+	return TextVal(s)
+}
+
+// Parse a number with optional thousands separators and percent sign.
+func parseNumber(s string) (float64, bool) {
+	percent := strings.HasSuffix(s, "%")
+	if percent {
+		s = strings.TrimSpace(s[:len(s)-1])
+	}
+	if strings.Contains(s, ",") {
+		// Separators must split the integer part into groups of three.
+		i := strings.IndexAny(s, ".eE")
+		if i < 0 {
+			i = len(s)
+		}
+		groups := strings.Split(strings.TrimLeft(s[:i], "+-"), ",")
+		for j, g := range groups {
+			if len(g) > 3 || j > 0 && len(g) != 3 || g == "" {
+				return 0, false
+			}
+		}
+		s = strings.Replace(s, ",", "", -1)
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || strings.ContainsAny(s, "xXpPnN_") {
+		return 0, false
+	}
+	if percent {
+		n /= 100
+	}
+	return n, true
+}
+
+// Coerce a value to a number.
+func (v Value) toNumber() (float64, *Value) {
+	switch v.kind {
+	case EmptyKind:
+		return 0, nil
+	case TextKind:
+		if n, ok := parseNumber(strings.TrimSpace(v.str)); ok {
+			return n, nil
+		}
+		e := ErrorVal(errValue)
+		return 0, &e
+	case ErrorKind:
+		return 0, &v
+	}
+	n, _ := v.Number()
+	return n, nil
+}
+
+// Coerce a value to a boolean.
+func (v Value) toBool() (bool, *Value) {
+	switch v.kind {
+	case BoolKind:
+		return v.b, nil
+	case TextKind:
+		switch strings.ToUpper(v.str) {
+		case "TRUE":
+			return true, nil
+		case "FALSE":
+			return false, nil
+		}
+		e := ErrorVal(errValue)
+		return false, &e
+	case ErrorKind:
+		return false, &v
+	}
+	n, _ := v.toNumber()
+	return n != 0, nil
+}
+
+// Compare two values for sorting. Numbers and dates sort before text,
+// text before booleans and booleans before errors. Empty values sort
+// last. Text compares case insensitively.
+func (v Value) Compare(w Value) int {
+	rank := func(v Value) int {
+		switch v.kind {
+		case TextKind:
+			return 1
+		case BoolKind:
+			return 2
+		case ErrorKind:
+			return 3
+		case EmptyKind:
+			return 4
+		}
+		return 0
+	}
+	if c := rank(v) - rank(w); c != 0 {
+		return c
+	}
+	switch rank(v) {
+	case 1, 3:
+		return strings.Compare(strings.ToLower(v.str), strings.ToLower(w.str))
+	case 4:
+		return 0
+	}
+	a, _ := v.Number()
+	b, _ := w.Number()
+	return cmpFloat(a, b)
+}
+
+func cmpFloat(a, b float64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package grid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateVal(t *testing.T) {
+	tests := []struct {
+		t   time.Time
+		num float64
+	}{
+		{time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC), 0},
+		{time.Date(1900, 1, 1, 12, 0, 0, 0, time.UTC), 2.5},
+		{time.Date(2024, 2, 29, 6, 0, 0, 0, time.UTC), 45351.25},
+		{time.Date(1899, 12, 29, 18, 0, 0, 0, time.UTC), -0.25},
+		{time.Date(2500, 6, 1, 0, 0, 0, 0, time.UTC), 219299},
+		{time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC), 2958465.999988426},
+	}
+	for _, tt := range tests {
+		v := DateVal(tt.t)
+		if n, _ := v.Number(); abs(n-tt.num) > 1e-9 {
+			t.Errorf("DateVal(%s) = %v, want %v", tt.t, n, tt.num)
+		}
+		if got, ok := v.Time(); !ok || !got.Equal(tt.t) {
+			t.Errorf("DateVal(%s).Time() = %s, %v", tt.t, got, ok)
+		}
+	}
+	if _, ok := NumberVal(1).Time(); ok {
+		t.Errorf("a number has a time")
+	}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		s    string
+		kind ValueKind
+		want string
+	}{
+		{"", EmptyKind, ""},
+		{"  ", EmptyKind, ""},
+		{"42", NumberKind, "42"},
+		{" -1.5e3 ", NumberKind, "-1500"},
+		{"1,234,567.5", NumberKind, "1234567.5"},
+		{"12,34", TextKind, "12,34"},
+		{"50%", NumberKind, "0.5"},
+		{"0x10", TextKind, "0x10"},
+		{"NaN", TextKind, "NaN"},
+		{"Inf", TextKind, "Inf"},
+		{"true", BoolKind, "TRUE"},
+		{"FALSE", BoolKind, "FALSE"},
+		{"#div/0!", ErrorKind, errDiv0},
+		{"2024-03-05", DateKind, "2024-03-05"},
+		{"3/5/2024 14:30", DateKind, "2024-03-05 14:30:00"},
+		{"14:30", DateKind, "14:30:00"},
+		{"'42", TextKind, "42"},
+		{"'=A1", TextKind, "=A1"},
+		{"hello", TextKind, "hello"},
+	}
+	for _, tt := range tests {
+		v := ParseValue(tt.s)
+		if v.Kind() != tt.kind || v.String() != tt.want {
+			t.Errorf("ParseValue(%q) = %v %q, want %v %q", tt.s, v.Kind(), v.String(), tt.kind, tt.want)
+		}
+		// The input text of a value parses back to the same value.
+		if tt.kind != EmptyKind {
+			if w := ParseValue(v.input()); w != v {
+				t.Errorf("ParseValue(%q) = %v, want %v", v.input(), w, v)
+			}
+		}
+	}
+}
@@ -1,3 +1,6 @@
+//go:build js && wasm
+// +build js,wasm
+
 package main
 
 import (
@@ -10,5 +13,40 @@ func main() {
 	js.Global().Set("newGrid", js.FuncOf(grid.NewGridJs))
 	js.Global().Set("setCssMap", js.FuncOf(grid.SetCssMap))
 	js.Global().Set("addData", js.FuncOf(grid.AddData))
+	js.Global().Set("setCellFormat", js.FuncOf(grid.SetCellFormat))
+	js.Global().Set("setColumnFormat", js.FuncOf(grid.SetColumnFormat))
+	js.Global().Set("setRangeFormat", js.FuncOf(grid.SetRangeFormat))
+	js.Global().Set("setColumnWidth", js.FuncOf(grid.SetColumnWidth))
+	js.Global().Set("setRowHeight", js.FuncOf(grid.SetRowHeight))
+	js.Global().Set("setColumnHeader", js.FuncOf(grid.SetColumnHeader))
+	js.Global().Set("setRowHeader", js.FuncOf(grid.SetRowHeader))
+	js.Global().Set("freezePanes", js.FuncOf(grid.FreezePanes))
+	js.Global().Set("undo", js.FuncOf(grid.Undo))
+	js.Global().Set("redo", js.FuncOf(grid.Redo))
+	js.Global().Set("canUndo", js.FuncOf(grid.CanUndo))
+	js.Global().Set("canRedo", js.FuncOf(grid.CanRedo))
+	js.Global().Set("beginUpdate", js.FuncOf(grid.BeginUpdate))
+	js.Global().Set("endUpdate", js.FuncOf(grid.EndUpdate))
+	js.Global().Set("getSelectedRanges", js.FuncOf(grid.GetSelection))
+	js.Global().Set("setCellAlignment", js.FuncOf(grid.SetCellAlignment))
+	js.Global().Set("setColumnAlignment", js.FuncOf(grid.SetColumnAlignment))
+	js.Global().Set("setRangeAlignment", js.FuncOf(grid.SetRangeAlignment))
+	js.Global().Set("setCellStyle", js.FuncOf(grid.SetCellStyle))
+	js.Global().Set("setRowStyle", js.FuncOf(grid.SetRowStyle))
+	js.Global().Set("setColumnStyle", js.FuncOf(grid.SetColumnStyle))
+	js.Global().Set("setRangeStyle", js.FuncOf(grid.SetRangeStyle))
+	js.Global().Set("outlineRange", js.FuncOf(grid.OutlineRange))
+	js.Global().Set("setRangeBorders", js.FuncOf(grid.SetRangeBorders))
+	js.Global().Set("clearRangeBorders", js.FuncOf(grid.ClearRangeBorders))
+	js.Global().Set("mergeCells", js.FuncOf(grid.MergeCells))
+	js.Global().Set("unmergeCells", js.FuncOf(grid.UnmergeCells))
+	js.Global().Set("getMerges", js.FuncOf(grid.GetMerges))
+	js.Global().Set("addRow", js.FuncOf(grid.AddRow))
+	js.Global().Set("addColumn", js.FuncOf(grid.AddColumn))
+	js.Global().Set("deleteRow", js.FuncOf(grid.DeleteRow))
+	js.Global().Set("deleteColumn", js.FuncOf(grid.DeleteColumn))
+	js.Global().Set("importCSV", js.FuncOf(grid.ImportCSV))
+	js.Global().Set("exportCSV", js.FuncOf(grid.ExportCSV))
+	js.Global().Set("downloadCSV", js.FuncOf(grid.DownloadCSV))
 	<-c
 }